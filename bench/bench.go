@@ -0,0 +1,153 @@
+//Benchmarks for the basic tree operations, run with go run ./bench
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zot/go-fingertree/typed"
+)
+
+//countMeasurer counts items, it implements typed.MeasurerOf[int, int]
+type countMeasurer struct{}
+
+func (countMeasurer) Identity() int          { return 0 }
+func (countMeasurer) Measure(i int) int      { return 1 }
+func (countMeasurer) Sum(m1 int, m2 int) int { return m1 + m2 }
+
+//treeSize the number of items in the trees the benchmarks start with
+const treeSize = 10000
+
+//buildSize the number of items in the trees the build benchmarks make
+const buildSize = 1000000
+
+func tree(n int) typed.Fingertree[int, int] {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	return typed.FromSlice[int, int](countMeasurer{}, items)
+}
+
+//benchmarks each one performs b.N operations on trees of treeSize
+//items, forcing lazy results with Measure or PeekFirst so that the
+//deferred work is counted
+var benchmarks = []struct {
+	name string
+	run  func(b *testing.B)
+}{
+	{"AddFirst", func(b *testing.B) {
+		t := typed.Empty[int, int](countMeasurer{})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			t = t.AddFirst(i)
+		}
+	}},
+	{"AddLast", func(b *testing.B) {
+		t := typed.Empty[int, int](countMeasurer{})
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			t = t.AddLast(i)
+		}
+	}},
+	{"RemoveFirst", func(b *testing.B) {
+		full := tree(treeSize)
+		t := full
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if t.IsEmpty() {
+				t = full
+			}
+			t = t.RemoveFirst()
+			t.PeekFirst()
+		}
+	}},
+	{"Concat", func(b *testing.B) {
+		t1, t2 := tree(treeSize), tree(treeSize)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			t1.Concat(t2).Measure()
+		}
+	}},
+	{"Split", func(b *testing.B) {
+		t := tree(treeSize)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			n := (i * 7919) % treeSize
+			s := t.Split(func(m int) bool { return m > n })
+			s[0].Measure()
+			s[1].Measure()
+		}
+	}},
+	{"Each", func(b *testing.B) {
+		t := tree(treeSize)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sum := 0
+			t.Each(func(item int) bool {
+				sum += item
+				return true
+			})
+		}
+	}},
+	{"Iterator", func(b *testing.B) {
+		t := tree(treeSize)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sum := 0
+			it := typed.NewIterator(t)
+			for item, ok := it.Next(); ok; item, ok = it.Next() {
+				sum += item
+			}
+		}
+	}},
+	{"FromSlice", func(b *testing.B) {
+		items := make([]int, buildSize)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			typed.FromSlice[int, int](countMeasurer{}, items)
+		}
+	}},
+	{"BuildParallel", func(b *testing.B) {
+		items := make([]int, buildSize)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			typed.BuildParallel[int, int](countMeasurer{}, items, 0)
+		}
+	}},
+	{"EachChunked", func(b *testing.B) {
+		items := make([]int, treeSize)
+		for i := range items {
+			items[i] = i
+		}
+		t := typed.NewChunked[int, int](countMeasurer{}, 64, items...)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sum := 0
+			t.EachChunk(func(chunk []int) bool {
+				for _, item := range chunk {
+					sum += item
+				}
+				return true
+			})
+		}
+	}},
+}
+
+func main() {
+	for _, bm := range benchmarks {
+		r := testing.Benchmark(bm.run)
+		fmt.Printf("%-14s %10d %12s %8d B/op %6d allocs/op\n",
+			bm.name, r.N, fmt.Sprintf("%d ns/op", r.NsPerOp()), r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+}
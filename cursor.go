@@ -0,0 +1,54 @@
+package fingertree
+
+import "github.com/zot/go-fingertree/typed"
+
+//Cursor a position in a tree with a focus item, see typed.Cursor
+type Cursor struct {
+	c *typed.Cursor[TreeItem, MeasureValue]
+}
+
+//NewCursor return a cursor focused on the first item of t that
+//satisfies p or a cursor past the last item if none does
+func NewCursor(t Fingertree, p Predicate) *Cursor {
+	return &Cursor{typed.NewCursor(t.Typed(), p)}
+}
+
+//NewCursorAt return a cursor focused on the item at index i of t,
+//or past the last item if i >= t.Len()
+func NewCursorAt(t Fingertree, i int) *Cursor {
+	return &Cursor{typed.NewCursorAt(t.Typed(), i)}
+}
+
+//Focus return the item at the cursor and whether there is one
+func (c *Cursor) Focus() (TreeItem, bool) { return c.c.Focus() }
+
+//Prefix return the measurement of the items before the focus
+func (c *Cursor) Prefix() MeasureValue { return c.c.Prefix() }
+
+//Index return the index of the focus
+func (c *Cursor) Index() int { return c.c.Index() }
+
+//Left return a tree of the items before the focus
+func (c *Cursor) Left() Fingertree { return Wrap(c.c.Left()) }
+
+//Right return a tree of the items after the focus
+func (c *Cursor) Right() Fingertree { return Wrap(c.c.Right()) }
+
+//Next move the cursor to the next item, returns whether there is one
+func (c *Cursor) Next() bool { return c.c.Next() }
+
+//Prev move the cursor to the previous item, returns false if the
+//cursor was already at the first item
+func (c *Cursor) Prev() bool { return c.c.Prev() }
+
+//Insert add item at the cursor, it becomes the focus
+func (c *Cursor) Insert(item TreeItem) { c.c.Insert(item) }
+
+//Delete remove the focus, returns whether there was one
+func (c *Cursor) Delete() bool { return c.c.Delete() }
+
+//Replace change the focus to item, returns whether there was one
+func (c *Cursor) Replace(item TreeItem) bool { return c.c.Replace(item) }
+
+//Close return a tree with the cursor's items
+func (c *Cursor) Close() Fingertree { return Wrap(c.c.Close()) }
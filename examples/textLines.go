@@ -28,29 +28,36 @@ func newLineMeasurer() *ft.Measurer {
 }
 
 func characterOffset(t ft.Fingertree, offset int) {
-	s := t.Split(func(m ft.MeasureValue) bool {
+	_, line, _, prefix, ok := t.SplitAt3(func(m ft.MeasureValue) bool {
 		return m.(*lineMeasure).char > offset
 	})
-	m1 := s[0].Measure().(*lineMeasure)
-	line := s[1].PeekFirst()
-	if line == nil {
+	m1 := prefix.(*lineMeasure)
+	if !ok {
 		line = "EOF"
 	}
 	fmt.Printf("offset %d, line %d:%d: %s\n", offset, m1.line, m1.char, line)
 }
 
 func lineOffset(t ft.Fingertree, offset int) {
-	s := t.Split(func(m ft.MeasureValue) bool {
+	_, line, _, prefix, ok := t.SplitAt3(func(m ft.MeasureValue) bool {
 		return m.(*lineMeasure).line > offset
 	})
-	m1 := s[0].Measure().(*lineMeasure)
-	line := s[1].PeekFirst()
-	if line == nil {
+	m1 := prefix.(*lineMeasure)
+	if !ok {
 		line = "EOF"
 	}
 	fmt.Printf("line %d:%d: %s\n", m1.line, m1.char, line)
 }
 
+//render print each line with its line and character offsets
+func render(t ft.Fingertree) {
+	t.EachWithMeasure(func(line ft.TreeItem, before, _ ft.MeasureValue) bool {
+		m := before.(*lineMeasure)
+		fmt.Printf("%d:%d: %s\n", m.line, m.char, line)
+		return true
+	})
+}
+
 func main() {
 	t := ft.With(newLineMeasurer(), "this", "is", "a", "test")
 	characterOffset(t, 0)
@@ -66,4 +73,5 @@ func main() {
 	lineOffset(t, 2)
 	lineOffset(t, 3)
 	lineOffset(t, 4)
+	render(t)
 }
@@ -0,0 +1,26 @@
+package fingertree
+
+import "github.com/zot/go-fingertree/typed"
+
+//Filter return a tree of the items in t that match pred, see
+//typed.Filter
+func Filter(t Fingertree, pred Code) Fingertree {
+	return Wrap(typed.Filter(t.Typed(), pred))
+}
+
+//Partition return a tree of the items in t that match pred and a tree
+//of the ones that do not, see typed.Partition
+func Partition(t Fingertree, pred Code) (yes, no Fingertree) {
+	y, n := typed.Partition(t.Typed(), pred)
+	return Wrap(y), Wrap(n)
+}
+
+//GroupBy return trees of the items in t by their keys, which must be
+//comparable, see typed.GroupBy
+func GroupBy(t Fingertree, key func(TreeItem) interface{}) map[interface{}]Fingertree {
+	result := map[interface{}]Fingertree{}
+	for k, tree := range typed.GroupBy(t.Typed(), key) {
+		result[k] = Wrap(tree)
+	}
+	return result
+}
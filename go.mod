@@ -1,3 +1,3 @@
 module github.com/zot/go-fingertree
 
-go 1.14
+go 1.23
@@ -0,0 +1,45 @@
+package fingertree
+
+import (
+	"iter"
+
+	"github.com/zot/go-fingertree/typed"
+)
+
+//Iterator a position between two items of a tree, see typed.Iterator
+type Iterator = typed.Iterator[TreeItem]
+
+//NewIterator return an Iterator before the first item of t
+func NewIterator(t Fingertree) *Iterator {
+	return typed.NewIterator(t.Typed())
+}
+
+//IterFrom return an Iterator before the first item of t that
+//satisfies p or after the last item if none does
+func IterFrom(t Fingertree, p Predicate) *Iterator {
+	return typed.IterFrom(t.Typed(), p)
+}
+
+//IterAt return an Iterator before the item at index i of t or after
+//the last item if i >= t.Len()
+func IterAt(t Fingertree, i int) *Iterator {
+	return typed.IterAt(t.Typed(), i)
+}
+
+//FromSeq return a tree of the items in seq
+func FromSeq(m *Measurer, seq iter.Seq[TreeItem]) Fingertree {
+	return Wrap(typed.FromSeq(m.Of(), seq))
+}
+
+//Enumerate return an iter.Seq2 of the index and item of each item in
+//t, see typed.Enumerate
+func Enumerate(t Fingertree) iter.Seq2[int, TreeItem] {
+	return typed.Enumerate(t.Typed())
+}
+
+//KeyedBy return an iter.Seq2 of the key and item of each item in t,
+//keys must be comparable to collect them into a map, see
+//typed.KeyedBy
+func KeyedBy(t Fingertree, key func(TreeItem) interface{}) iter.Seq2[interface{}, TreeItem] {
+	return typed.KeyedBy(t.Typed(), key)
+}
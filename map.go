@@ -0,0 +1,15 @@
+package fingertree
+
+import "github.com/zot/go-fingertree/typed"
+
+//Remeasure return a tree with t's items measured with m and the same
+//shape as t, see typed.Remeasure
+func Remeasure(t Fingertree, m *Measurer) Fingertree {
+	return Wrap(typed.Remeasure(t.Typed(), m.Of()))
+}
+
+//Map return a tree with f of each of t's items, measured with m and
+//with the same shape as t, see typed.Map
+func Map(t Fingertree, f func(TreeItem) TreeItem, m *Measurer) Fingertree {
+	return Wrap(typed.Map(t.Typed(), f, m.Of()))
+}
@@ -0,0 +1,186 @@
+// Package measures provides common measurers for typed finger trees
+// and predicates to split and search trees with them.
+//
+// Count, Sum, KahanSum, Min, Max, LastKey, Any and All make
+// typed.MeasurerOf values and Product pairs two measurers into one,
+// so a tree can be indexed on both, like examples/textLines.go does
+// with a hand-written measure. The predicates, like CountGreaterThan
+// and KeyAtLeast, are typed.Predicates for the matching measures.
+package measures
+
+import "github.com/zot/go-fingertree/typed"
+
+// Number the types Sum can add
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Ordered the types Min, Max and LastKey can compare
+type Ordered interface {
+	Number | ~string
+}
+
+// Maybe a measurement that may not have a value, like the minimum
+// of no items
+type Maybe[K any] struct {
+	Value K
+	OK    bool
+}
+
+// Kahan a compensated floating point sum, Value returns the sum
+type Kahan struct {
+	Sum          float64
+	Compensation float64
+}
+
+// Pair two measurements of the same items
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+type count[T any] struct{}
+
+type sum[T any, N Number] struct {
+	value func(T) N
+}
+
+type kahanSum[T any] struct {
+	value func(T) float64
+}
+
+// extreme keeps the key that better prefers over the other
+type extreme[T any, K Ordered] struct {
+	key    func(T) K
+	better func(a, b K) bool
+}
+
+type lastKey[T any, K any] struct {
+	key func(T) K
+}
+
+type anyOf[T any] struct {
+	test func(T) bool
+}
+
+type allOf[T any] struct {
+	test func(T) bool
+}
+
+type product[T, A, B any] struct {
+	first  typed.MeasurerOf[T, A]
+	second typed.MeasurerOf[T, B]
+}
+
+// Count measure the number of items
+func Count[T any]() typed.MeasurerOf[T, int] { return count[T]{} }
+
+// Sum measure the total of value for the items
+func Sum[T any, N Number](value func(T) N) typed.MeasurerOf[T, N] { return sum[T, N]{value} }
+
+// KahanSum measure the total of value for the items with compensated
+// summation, so long sequences of floats do not accumulate rounding
+// errors
+func KahanSum[T any](value func(T) float64) typed.MeasurerOf[T, Kahan] {
+	return kahanSum[T]{value}
+}
+
+// Min measure the smallest key of the items
+func Min[T any, K Ordered](key func(T) K) typed.MeasurerOf[T, Maybe[K]] {
+	return extreme[T, K]{key, func(a, b K) bool { return a < b }}
+}
+
+// Max measure the largest key of the items
+func Max[T any, K Ordered](key func(T) K) typed.MeasurerOf[T, Maybe[K]] {
+	return extreme[T, K]{key, func(a, b K) bool { return a > b }}
+}
+
+// LastKey measure the key of the last item, for trees sorted by key
+// this finds items by key, like an ordered map
+func LastKey[T any, K any](key func(T) K) typed.MeasurerOf[T, Maybe[K]] {
+	return lastKey[T, K]{key}
+}
+
+// Any measure whether test is true for any of the items
+func Any[T any](test func(T) bool) typed.MeasurerOf[T, bool] { return anyOf[T]{test} }
+
+// All measure whether test is true for all of the items
+func All[T any](test func(T) bool) typed.MeasurerOf[T, bool] { return allOf[T]{test} }
+
+// Product measure items with both first and second
+func Product[T, A, B any](first typed.MeasurerOf[T, A], second typed.MeasurerOf[T, B]) typed.MeasurerOf[T, Pair[A, B]] {
+	return product[T, A, B]{first, second}
+}
+
+func (count[T]) Identity() int          { return 0 }
+func (count[T]) Measure(T) int          { return 1 }
+func (count[T]) Sum(m1 int, m2 int) int { return m1 + m2 }
+
+func (s sum[T, N]) Identity() N      { return 0 }
+func (s sum[T, N]) Measure(item T) N { return s.value(item) }
+func (s sum[T, N]) Sum(m1 N, m2 N) N { return m1 + m2 }
+
+func (k kahanSum[T]) Identity() Kahan   { return Kahan{} }
+func (k kahanSum[T]) Measure(i T) Kahan { return Kahan{Sum: k.value(i)} }
+
+// Sum add two compensated sums, keeping the rounding error of adding
+// their sums in the compensation (Neumaier's variant of Kahan's
+// algorithm, which works for sums of any size)
+func (k kahanSum[T]) Sum(m1 Kahan, m2 Kahan) Kahan {
+	total := m1.Sum + m2.Sum
+	var err float64
+	if abs(m1.Sum) >= abs(m2.Sum) {
+		err = (m1.Sum - total) + m2.Sum
+	} else {
+		err = (m2.Sum - total) + m1.Sum
+	}
+	return Kahan{total, m1.Compensation + m2.Compensation + err}
+}
+
+// Value return the compensated sum
+func (k Kahan) Value() float64 { return k.Sum + k.Compensation }
+
+func (e extreme[T, K]) Identity() Maybe[K]   { return Maybe[K]{} }
+func (e extreme[T, K]) Measure(i T) Maybe[K] { return Maybe[K]{e.key(i), true} }
+func (e extreme[T, K]) Sum(m1, m2 Maybe[K]) Maybe[K] {
+	if !m2.OK || (m1.OK && !e.better(m2.Value, m1.Value)) {
+		return m1
+	}
+	return m2
+}
+
+func (l lastKey[T, K]) Identity() Maybe[K]   { return Maybe[K]{} }
+func (l lastKey[T, K]) Measure(i T) Maybe[K] { return Maybe[K]{l.key(i), true} }
+func (l lastKey[T, K]) Sum(m1, m2 Maybe[K]) Maybe[K] {
+	if m2.OK {
+		return m2
+	}
+	return m1
+}
+
+func (a anyOf[T]) Identity() bool            { return false }
+func (a anyOf[T]) Measure(i T) bool          { return a.test(i) }
+func (a anyOf[T]) Sum(m1 bool, m2 bool) bool { return m1 || m2 }
+
+func (a allOf[T]) Identity() bool            { return true }
+func (a allOf[T]) Measure(i T) bool          { return a.test(i) }
+func (a allOf[T]) Sum(m1 bool, m2 bool) bool { return m1 && m2 }
+
+func (p product[T, A, B]) Identity() Pair[A, B] {
+	return Pair[A, B]{p.first.Identity(), p.second.Identity()}
+}
+func (p product[T, A, B]) Measure(i T) Pair[A, B] {
+	return Pair[A, B]{p.first.Measure(i), p.second.Measure(i)}
+}
+func (p product[T, A, B]) Sum(m1, m2 Pair[A, B]) Pair[A, B] {
+	return Pair[A, B]{p.first.Sum(m1.First, m2.First), p.second.Sum(m1.Second, m2.Second)}
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
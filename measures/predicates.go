@@ -0,0 +1,74 @@
+package measures
+
+import "github.com/zot/go-fingertree/typed"
+
+// CountGreaterThan match Count measurements over n, splitting with it
+// puts the first n items on the left
+func CountGreaterThan(n int) typed.Predicate[int] {
+	return func(m int) bool { return m > n }
+}
+
+// CountAtLeast match Count measurements of n or more, splitting with
+// it puts the first n-1 items on the left
+func CountAtLeast(n int) typed.Predicate[int] {
+	return func(m int) bool { return m >= n }
+}
+
+// SumGreaterThan match Sum measurements over n
+func SumGreaterThan[N Number](n N) typed.Predicate[N] {
+	return func(m N) bool { return m > n }
+}
+
+// SumAtLeast match Sum measurements of n or more
+func SumAtLeast[N Number](n N) typed.Predicate[N] {
+	return func(m N) bool { return m >= n }
+}
+
+// KahanGreaterThan match KahanSum measurements over n
+func KahanGreaterThan(n float64) typed.Predicate[Kahan] {
+	return func(m Kahan) bool { return m.Value() > n }
+}
+
+// KeyAtLeast match Max or LastKey measurements of k or more. With
+// LastKey on a tree sorted by key it finds the first item with a key
+// of at least k.
+func KeyAtLeast[K Ordered](k K) typed.Predicate[Maybe[K]] {
+	return func(m Maybe[K]) bool { return m.OK && m.Value >= k }
+}
+
+// KeyGreaterThan match Max or LastKey measurements over k. With
+// LastKey on a tree sorted by key it finds the first item with a key
+// over k.
+func KeyGreaterThan[K Ordered](k K) typed.Predicate[Maybe[K]] {
+	return func(m Maybe[K]) bool { return m.OK && m.Value > k }
+}
+
+// KeyAtMost match Min measurements of k or less, it finds the first
+// item with a key of at most k
+func KeyAtMost[K Ordered](k K) typed.Predicate[Maybe[K]] {
+	return func(m Maybe[K]) bool { return m.OK && m.Value <= k }
+}
+
+// AnyTrue match Any measurements that are true, it finds the first
+// item that passes the test
+func AnyTrue() typed.Predicate[bool] {
+	return func(m bool) bool { return m }
+}
+
+// NotAll match All measurements that are false, it finds the first
+// item that fails the test
+func NotAll() typed.Predicate[bool] {
+	return func(m bool) bool { return !m }
+}
+
+// First match Product measurements where p matches the first
+// measurement
+func First[A, B any](p typed.Predicate[A]) typed.Predicate[Pair[A, B]] {
+	return func(m Pair[A, B]) bool { return p(m.First) }
+}
+
+// Second match Product measurements where p matches the second
+// measurement
+func Second[A, B any](p typed.Predicate[B]) typed.Predicate[Pair[A, B]] {
+	return func(m Pair[A, B]) bool { return p(m.Second) }
+}
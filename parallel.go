@@ -0,0 +1,15 @@
+package fingertree
+
+import "github.com/zot/go-fingertree/typed"
+
+//ParallelFold return the sum under m of the measurements of t's items
+//using up to workers goroutines, see typed.ParallelFold
+func ParallelFold(t Fingertree, m *Measurer, workers int) MeasureValue {
+	return typed.ParallelFold(t.Typed(), m.Of(), workers)
+}
+
+//ParallelEach execute c on each item in t using up to workers
+//goroutines until c returns false, see typed.ParallelEach
+func ParallelEach(t Fingertree, workers int, c Code) bool {
+	return typed.ParallelEach(t.Typed(), workers, c)
+}
@@ -0,0 +1,17 @@
+package fingertree
+
+import "github.com/zot/go-fingertree/typed"
+
+//Reverse return a tree with t's items in reverse order, measured with
+//Dual of t's measurer. Only concatenate it with other trees measured
+//with the dual, such as other reversed trees, or reverse it again to
+//combine it with trees measured with t's measurer, see typed.Reverse
+func Reverse(t Fingertree) Fingertree {
+	return Wrap(typed.Reverse(t.Typed()))
+}
+
+//Dual return a measurer like m with the arguments to Sum swapped, see
+//typed.Dual
+func Dual(m MeasurerOf) MeasurerOf {
+	return typed.Dual(m)
+}
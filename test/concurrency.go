@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/zot/go-fingertree"
+	"github.com/zot/go-fingertree/typed"
+)
+
+//sharedTrees make trees with lots of unforced delayed middles and
+//unmeasured deeps for readers to share
+func sharedTrees() []typed.Fingertree[int, int] {
+	var trees []typed.Fingertree[int, int]
+
+	t := typed.Empty[int, int](countMeasurer{})
+	for i := 1; i <= 2000; i++ {
+		t = t.AddLast(i)
+	}
+	trees = append(trees, t, t.RemoveFirst().RemoveLast(), t.Drop(1).Take(1998))
+	split := t.SplitAt(777)
+	trees = append(trees, split[0].Concat(split[1]), split[1].Concat(split[0]))
+	for r := t; r.Len() > 1500; r = r.RemoveFirst() {
+		trees = append(trees, r)
+	}
+	return trees
+}
+
+//testConcurrency hammer shared trees from many goroutines, run with
+//go run -race to check that readers do not race
+func testConcurrency() {
+	trees := sharedTrees()
+	var wg sync.WaitGroup
+	errors := make(chan string, 64)
+
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < len(trees); n++ {
+				t := trees[(n*7+g)%len(trees)]
+				if (n+g)%5 == 0 {
+					t.Compact()
+				}
+				size := t.Len()
+				if t.Measure() != size {
+					errors <- fmt.Sprintf("Bad concurrent measure: expected %d but got %d", size, t.Measure())
+					return
+				}
+				count := 0
+				t.Each(func(int) bool {
+					count++
+					return true
+				})
+				if count != size {
+					errors <- fmt.Sprintf("Bad concurrent traversal: expected %d but got %d", size, count)
+					return
+				}
+				i := (g * 131) % size
+				if t.At(i) != t.Find(func(m int) bool { return m > i })[1] {
+					errors <- fmt.Sprintf("Bad concurrent find at %d", i)
+					return
+				}
+				if s := t.SplitAt(i); s[0].Measure() != i || s[1].Measure() != size-i {
+					errors <- fmt.Sprintf("Bad concurrent split at %d", i)
+					return
+				}
+				c := typed.NewCursorAt(t, i)
+				for c.Next() {
+				}
+				if c.Prefix() != size {
+					errors <- fmt.Sprintf("Bad concurrent cursor: expected %d but got %d", size, c.Prefix())
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errors)
+	for err := range errors {
+		panic(err)
+	}
+}
+
+//testParallel fold and traverse shared trees in parallel with an order
+//dependent monoid, which must give the same result as a sequential
+//fold
+func testParallel() {
+	concat := typed.NewMeasurer(
+		func() string { return "" },
+		func(i int) string { return strconv.Itoa(i) + "," },
+		func(a, b string) string { return a + b })
+	trees := sharedTrees()
+	items := ints(3000)
+	trees = append(trees,
+		typed.Empty[int, int](countMeasurer{}),
+		typed.With[int, int](countMeasurer{}, 7),
+		typed.NewChunked[int, int](countMeasurer{}, 16, items...),
+		typed.NewChunked[int, int](countMeasurer{}, 1, items[:100]...))
+	for _, t := range trees {
+		expected := ""
+		sum := int64(0)
+		t.Each(func(i int) bool {
+			expected += strconv.Itoa(i) + ","
+			sum += int64(i)
+			return true
+		})
+		for _, workers := range []int{0, 1, 2, 3, 8, 64} {
+			assertEqual(expected, typed.ParallelFold(t, concat, workers), "Bad ParallelFold")
+			got := int64(0)
+			assertEqual(true, typed.ParallelEach(t, workers, func(i int) bool {
+				atomic.AddInt64(&got, int64(i))
+				return true
+			}), "Bad ParallelEach")
+			assertEqual(sum, got, "Bad ParallelEach")
+			if t.Len() > 1 {
+				assertEqual(false, typed.ParallelEach(t, workers, func(i int) bool { return i != t.PeekLast() }),
+					"Bad ParallelEach stopping")
+			}
+		}
+	}
+	m := NewMeasurer(
+		func() MeasureValue { return 0 },
+		func(i TreeItem) MeasureValue { return i },
+		func(a, b MeasureValue) MeasureValue { return a.(int) + b.(int) })
+	t := With(m, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	assertEqual(55, ParallelFold(t, m, 4), "Bad untyped ParallelFold")
+	count := int64(0)
+	ParallelEach(t, 4, func(TreeItem) bool {
+		atomic.AddInt64(&count, 1)
+		return true
+	})
+	assertEqual(int64(10), count, "Bad untyped ParallelEach")
+}
+
+//testBuild build trees in parallel and from channels, they must have
+//the same items and measurements as trees built with AddLast
+func testBuild() {
+	sizes := []int{50000, 3*4096*2 + 7, 3*4096*2 + 8, 3*4096*2 + 9}
+	for n := 0; n <= 100; n++ {
+		sizes = append(sizes, n)
+	}
+	for _, n := range sizes {
+		items := ints(n)
+		expected := typed.Empty[int, int](sumMeasurer{})
+		for _, item := range items {
+			expected = expected.AddLast(item)
+		}
+		same := func(t typed.Fingertree[int, int], msg string) {
+			assertEqual(expected.Measure(), t.Measure(), msg)
+			assertEqual(expected.Len(), t.Len(), msg)
+			assertEqual(fmt.Sprint(typed.Items(expected)), fmt.Sprint(typed.Items(t)), msg)
+			for i := 0; i < n; i += 1 + n/50 {
+				assertEqual(i+1, t.At(i), msg)
+				assertEqual(i*(i+1)/2, t.SplitAt(i)[0].Measure(), msg)
+			}
+		}
+		for _, workers := range []int{0, 1, 3, 8} {
+			same(typed.BuildParallel[int, int](sumMeasurer{}, items, workers), "Bad BuildParallel")
+		}
+		ch := make(chan int, 100)
+		go func() {
+			for _, item := range items {
+				ch <- item
+			}
+			close(ch)
+		}()
+		same(typed.BuildFromChannel[int, int](sumMeasurer{}, ch), "Bad BuildFromChannel")
+		same(typed.FromSeq[int, int](sumMeasurer{}, slices.Values(items)), "Bad FromSeq")
+	}
+	m := NewMeasurer(
+		func() MeasureValue { return 0 },
+		func(i TreeItem) MeasureValue { return 1 },
+		func(a, b MeasureValue) MeasureValue { return a.(int) + b.(int) })
+	items := make([]TreeItem, 20000)
+	for i := range items {
+		items[i] = i
+	}
+	assertEqual(20000, BuildParallel(m, items, 4).Measure(), "Bad untyped BuildParallel")
+	ch := make(chan TreeItem)
+	go func() {
+		for _, item := range items {
+			ch <- item
+		}
+		close(ch)
+	}()
+	t := BuildFromChannel(m, ch)
+	assertEqual(20000, t.Measure(), "Bad untyped BuildFromChannel")
+	assertEqual(19999, t.PeekLast(), "Bad untyped BuildFromChannel")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/zot/go-fingertree/measures"
+	"github.com/zot/go-fingertree/typed"
+)
+
+//testMeasures split and find with the standard measurers
+func testMeasures() {
+	items := ints(100)
+	count := typed.FromSlice(measures.Count[int](), items)
+	assertEqual(100, count.Measure(), "Bad count measure")
+	assertEqual(10, count.Split(measures.CountGreaterThan(10))[0].Len(), "Bad count split")
+	assertEqual(9, count.Split(measures.CountAtLeast(10))[0].Len(), "Bad count split")
+	sum := typed.FromSlice(measures.Sum(func(i int) int { return i }), items)
+	assertEqual(5050, sum.Measure(), "Bad sum measure")
+	assertEqual(10, sum.Find(measures.SumAtLeast(55))[1], "Bad sum find")
+	assertEqual(11, sum.Find(measures.SumGreaterThan(55))[1], "Bad sum find")
+	floats := typed.With(measures.Sum(func(f float64) float64 { return f }), 0.5, 0.25, 0.125)
+	assertEqual(0.875, floats.Measure(), "Bad float sum measure")
+
+	values := []float64{1, 1e100, 1, -1e100}
+	naive := typed.FromSlice(measures.Sum(func(f float64) float64 { return f }), values)
+	kahan := typed.FromSlice(measures.KahanSum(func(f float64) float64 { return f }), values)
+	assertEqual(0.0, naive.Measure(), "Bad naive float sum")
+	assertEqual(2.0, kahan.Measure().Value(), "Bad Kahan sum")
+	assertEqual(1e100, kahan.Find(measures.KahanGreaterThan(1))[1], "Bad Kahan find")
+
+	keys := []int{5, 3, 8, 1, 9, 2}
+	lo := typed.FromSlice(measures.Min(func(i int) int { return i }), keys)
+	hi := typed.FromSlice(measures.Max(func(i int) int { return i }), keys)
+	assertEqual(measures.Maybe[int]{Value: 1, OK: true}, lo.Measure(), "Bad min measure")
+	assertEqual(measures.Maybe[int]{Value: 9, OK: true}, hi.Measure(), "Bad max measure")
+	assertEqual(false, typed.Empty(measures.Min(func(i int) int { return i })).Measure().OK, "Bad empty min")
+	assertEqual(1, lo.Locate(measures.KeyAtMost(4)).Index, "Bad min find")
+	assertEqual(3, lo.Locate(measures.KeyAtMost(2)).Index, "Bad min find")
+	assertEqual(2, hi.Locate(measures.KeyGreaterThan(5)).Index, "Bad max find")
+
+	words := []string{"apple", "banana", "cherry", "grape", "melon"}
+	sorted := typed.FromSlice(measures.LastKey(func(s string) string { return s }), words)
+	assertEqual("cherry", sorted.Find(measures.KeyAtLeast("c"))[1], "Bad last key find")
+	assertEqual("grape", sorted.Find(measures.KeyGreaterThan("cherry"))[1], "Bad last key find")
+	assertEqual(2, sorted.Split(measures.KeyAtLeast("c"))[0].Len(), "Bad last key split")
+
+	even := func(i int) bool { return i%2 == 0 }
+	anyEven := typed.FromSlice(measures.Any(even), []int{1, 3, 5, 6, 7})
+	allOdd := typed.FromSlice(measures.All(func(i int) bool { return !even(i) }), []int{1, 3, 5, 6, 7})
+	assertEqual(true, anyEven.Measure(), "Bad any measure")
+	assertEqual(false, allOdd.Measure(), "Bad all measure")
+	assertEqual(6, anyEven.Find(measures.AnyTrue())[1], "Bad any find")
+	assertEqual(6, allOdd.Find(measures.NotAll())[1], "Bad all find")
+
+	lines := typed.With(
+		measures.Product(measures.Count[string](), measures.Sum(func(s string) int { return len(s) })),
+		"this", "is", "a", "test")
+	assertEqual(measures.Pair[int, int]{First: 4, Second: 11}, lines.Measure(), "Bad product measure")
+	_, line, _, prefix, _ := lines.SplitAt3(measures.Second[int](measures.SumGreaterThan(6)))
+	assertEqual("a", line, "Bad product split")
+	assertEqual(measures.Pair[int, int]{First: 2, Second: 6}, prefix, "Bad product split")
+	assertEqual("test", lines.Find(measures.First[int, int](measures.CountGreaterThan(3)))[1], "Bad product find")
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"fmt"
+	"maps"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
 
 	. "github.com/zot/go-fingertree"
+	"github.com/zot/go-fingertree/typed"
 )
 
 func testTree(t Fingertree) {
@@ -90,6 +95,773 @@ func main() {
 			assertEqual(i+1, f[1], "Bad second result in find")
 		}
 	}
+	t2 := With(m)
+	for i := 101; i <= 200; i++ {
+		t2 = t2.AddLast(i)
+	}
+	assertRange(1, 200, t.Concat(t2))
+	assertRange(1, 200, t.Split(func(m MeasureValue) bool { return m.(int) > 50 })[0].Concat(
+		t.Split(func(m MeasureValue) bool { return m.(int) > 50 })[1].Concat(t2)))
+	assertRange(1, 100, WithMeasurerOf(m.Of()).Concat(t))
+	for i := -1; i <= 101; i++ {
+		split := t.SplitAt(i)
+		n := int(math.Min(100, math.Max(0, float64(i))))
+		assertRange(1, n, split[0])
+		assertRange(n+1, 100, split[1])
+		assertRange(1, n, t.Take(i))
+		assertRange(n+1, 100, t.Drop(i))
+		if i < 0 || i >= 100 {
+			assertEqual(nil, t.At(i), "Bad item at index")
+		} else {
+			assertEqual(i+1, t.At(i), "Bad item at index")
+		}
+	}
+	for i := -1; i <= 101; i++ {
+		left, pivot, right, prefix, ok := t.SplitAt3(func(m MeasureValue) bool { return m.(int) > i })
+		n := int(math.Min(100, math.Max(0, float64(i))))
+		assertRange(1, n, left)
+		assertEqual(n, prefix, "Bad SplitAt3 prefix")
+		assertEqual(i < 100, ok, "Bad SplitAt3 ok")
+		if ok {
+			assertEqual(n+1, pivot, "Bad SplitAt3 pivot")
+			assertRange(n+2, 100, right)
+		} else {
+			assertEqual(nil, pivot, "Bad SplitAt3 pivot")
+			assertEqual(true, right.IsEmpty(), "Bad SplitAt3 right")
+		}
+	}
+	for i := -1; i <= 101; i++ {
+		r := t.Locate(func(m MeasureValue) bool { return m.(int) > i })
+		n := int(math.Min(100, math.Max(0, float64(i))))
+		assertEqual(n > 0, r.HasBefore, "Bad Locate HasBefore")
+		assertEqual(i < 100, r.HasAt, "Bad Locate HasAt")
+		assertEqual(n, r.Index, "Bad Locate index")
+		assertEqual(n, r.Prefix, "Bad Locate prefix")
+		if r.HasBefore {
+			assertEqual(n, r.Before, "Bad Locate before")
+		}
+		if r.HasAt {
+			assertEqual(n+1, r.At, "Bad Locate at")
+			assertEqual(n+1, r.Measure, "Bad Locate measure")
+		} else {
+			assertEqual(n, r.Measure, "Bad Locate measure")
+		}
+	}
+	nils := With(m, nil, 1, nil)
+	first, ok := nils.PeekFirstOK()
+	assertEqual(nil, first, "Bad nil first item")
+	assertEqual(true, ok, "Bad nil first item")
+	_, ok = With(m).PeekLastOK()
+	assertEqual(false, ok, "Bad empty last item")
+	r := nils.Locate(func(m MeasureValue) bool { return m.(int) > 2 })
+	assertEqual(true, r.HasAt && r.HasBefore && r.At == nil && r.Before == 1, "Bad nil Locate")
+	for n := 0; n <= 120; n++ {
+		left, pivot, right, prefix, ok := t.Take(n).Search(func(l, r MeasureValue) bool { return l.(int) >= r.(int) })
+		assertEqual(n > 0, ok, "Bad Search ok")
+		if ok {
+			mid := int(math.Min(100, float64(n))+1) / 2
+			assertRange(1, mid-1, left)
+			assertEqual(mid, pivot, "Bad Search pivot")
+			assertEqual(mid-1, prefix, "Bad Search prefix")
+			assertRange(mid+1, int(math.Min(100, float64(n))), right)
+		}
+	}
+	testCursor(t)
+	testTyped()
+	testLazyMeasure()
+	testStrict()
+	testCounting()
+	testChunked()
+	testSplittableItem()
+	testIterators(m, t)
+	testEachWithMeasure(t)
+	testRanges(t)
+	testMap(m, t)
+	testFilter(t)
+	testReverse(t)
+	testMeasures()
+	testConcurrency()
+	testParallel()
+	testBuild()
+}
+
+func testCursor(t Fingertree) {
+	c := NewCursor(t, func(m MeasureValue) bool { return m.(int) > 10 })
+	item, ok := c.Focus()
+	assertEqual(11, item, "Bad cursor focus")
+	assertEqual(true, ok, "Bad cursor focus")
+	assertEqual(10, c.Prefix(), "Bad cursor prefix")
+	for i := 12; i <= 100; i++ {
+		assertEqual(true, c.Next(), "Bad cursor next")
+		item, _ = c.Focus()
+		assertEqual(i, item, "Bad cursor next")
+		assertEqual(i-1, c.Prefix(), "Bad cursor prefix")
+	}
+	assertEqual(false, c.Next(), "Bad cursor next past end")
+	_, ok = c.Focus()
+	assertEqual(false, ok, "Bad cursor focus past end")
+	assertEqual(100, c.Index(), "Bad cursor index")
+	c.Insert(101)
+	assertRange(1, 101, c.Close())
+	for i := 101; i > 1; i-- {
+		assertEqual(true, c.Prev(), "Bad cursor prev")
+		item, _ = c.Focus()
+		assertEqual(i-1, item, "Bad cursor prev")
+		assertEqual(i-2, c.Prefix(), "Bad cursor prefix")
+	}
+	assertEqual(false, c.Prev(), "Bad cursor prev before start")
+	assertEqual(true, c.Delete(), "Bad cursor delete")
+	assertRange(2, 101, c.Close())
+	c.Insert(1)
+	assertRange(1, 101, c.Close())
+	c = NewCursorAt(t, 49)
+	c.Replace(0)
+	c.Next()
+	c.Delete()
+	assertEqual(99, c.Close().Measure(), "Bad cursor delete")
+	assertEqual(0, c.Close().At(49), "Bad cursor replace")
+	assertEqual(52, c.Close().At(50), "Bad cursor delete")
+	assertRange(1, 100, t)
+}
+
+//countMeasurer counts items, it implements typed.MeasurerOf[int, int]
+type countMeasurer struct{}
+
+func (countMeasurer) Identity() int          { return 0 }
+func (countMeasurer) Measure(i int) int      { return 1 }
+func (countMeasurer) Sum(m1 int, m2 int) int { return m1 + m2 }
+
+func testTyped() {
+	t := typed.With[int, int](countMeasurer{})
+	for i := 1; i <= 100; i++ {
+		t = t.AddLast(i)
+	}
+	assertEqual(100, t.Measure(), "Bad typed measure")
+	assertEqual(1, t.PeekFirst(), "Bad typed first item")
+	assertEqual(100, t.PeekLast(), "Bad typed last item")
+	for i := 0; i <= 100; i++ {
+		split := t.Split(func(m int) bool { return m > i })
+		f := t.Find(func(m int) bool { return m > i })
+		assertEqual(i, split[0].Measure(), "Bad typed split")
+		assertEqual(100-i, split[1].Measure(), "Bad typed split")
+		assertEqual(i, f[0], "Bad first result in typed find")
+		if i < 100 {
+			assertEqual(i+1, f[1], "Bad second result in typed find")
+			assertEqual(i+1, split[1].PeekFirst(), "Bad typed split")
+		}
+		assertEqual(i, split[0].Len(), "Bad typed split length")
+		assertEqual(100, split[0].Concat(split[1]).Len(), "Bad typed concat length")
+		joined := typed.Items(split[0].Concat(split[1]))
+		assertEqual(100, len(joined), "Bad typed concat")
+		for j, item := range joined {
+			assertEqual(j+1, item, "Bad typed concat")
+		}
+	}
+	for n := 0; n <= 200; n++ {
+		items := ints(n)
+		bulk := typed.FromSlice[int, int](countMeasurer{}, items)
+		assertEqual(n, bulk.Measure(), "Bad bulk measure")
+		assertEqual(n, bulk.Len(), "Bad bulk length")
+		assertEqual(n, len(typed.Items(bulk)), "Bad bulk items")
+		for i, item := range typed.Items(bulk.AddFirst(0).AddLast(n + 1)) {
+			assertEqual(i, item, "Bad bulk items")
+		}
+		for i := 0; i < n; i += 7 {
+			assertEqual(i+1, bulk.At(i), "Bad bulk item at index")
+			assertEqual(i, bulk.SplitAt(i)[0].Len(), "Bad bulk split")
+		}
+	}
+	for n := 0; n <= 30; n++ {
+		items := make([]int, n)
+		for i := range items {
+			items[i] = 1000 + i
+		}
+		for _, size := range []int{0, 1, 2, 5, 9, 40} {
+			base := typed.FromSlice[int, int](countMeasurer{}, make([]int, size))
+			assertEqual(n+size, base.AddAllFirst(items...).Len(), "Bad AddAllFirst")
+			assertEqual(n+size, base.AddAllLast(items...).Measure(), "Bad AddAllLast")
+			if n > 0 {
+				assertEqual(1000, base.AddAllFirst(items...).PeekFirst(), "Bad AddAllFirst")
+			}
+			joined := base.ConcatWith(items, base)
+			assertEqual(n+2*size, joined.Len(), "Bad ConcatWith")
+			for i := range items {
+				assertEqual(items[i], joined.At(size+i), "Bad ConcatWith")
+				assertEqual(items[i], base.AddAllLast(items...).At(size+i), "Bad AddAllLast")
+			}
+		}
+	}
+	for t = t.RemoveFirst(); !t.IsEmpty(); t = t.RemoveLast() {
+		assertEqual(t.PeekLast()-t.PeekFirst()+1, t.Measure(), "Bad typed remove")
+		assertEqual(t.Measure(), t.Len(), "Bad typed length")
+		assertEqual(t.PeekLast(), t.At(t.Len()-1), "Bad typed item at index")
+	}
+}
+
+//sumMeasurer sums items, it implements typed.MeasurerOf[int, int]
+type sumMeasurer struct{}
+
+func (sumMeasurer) Identity() int          { return 0 }
+func (sumMeasurer) Measure(i int) int      { return i }
+func (sumMeasurer) Sum(m1 int, m2 int) int { return m1 + m2 }
+
+//testLazyMeasure measure trees with delayed middles before anything
+//forces them, so the measurements come from the delayed trees' parts
+func testLazyMeasure() {
+	sum := func(first, last int) int { return (first + last) * (last - first + 1) / 2 }
+	items := ints(500)
+	t := typed.FromSlice[int, int](sumMeasurer{}, items)
+	front, back := t, t
+	for i := 1; i < 500; i++ {
+		front = front.RemoveFirst()
+		back = back.RemoveLast()
+		assertEqual(sum(i+1, 500), front.Measure(), "Bad lazy measure after RemoveFirst")
+		assertEqual(sum(1, 500-i), back.Measure(), "Bad lazy measure after RemoveLast")
+	}
+	for i := 0; i <= 500; i += 13 {
+		split := t.SplitAt(i)
+		assertEqual(sum(1, i), split[0].Measure(), "Bad lazy measure after split")
+		assertEqual(sum(i+1, 500), split[1].Measure(), "Bad lazy measure after split")
+		joined := split[1].ConcatWith([]int{1000, 2000, 3000}, split[0])
+		assertEqual(sum(1, 500)+6000, joined.Measure(), "Bad lazy measure after concat")
+		assertEqual(sum(i+1, 500)+6000, joined.Take(500-i+3).Measure(), "Bad lazy measure after concat")
+	}
+}
+
+//testStrict check that strict trees and compacted lazy trees have the
+//same items and measurements as lazy ones
+func testStrict() {
+	items := ints(300)
+	lazy := typed.FromSlice[int, int](sumMeasurer{}, items)
+	strict := typed.FromSlice(typed.Strict[int, int](sumMeasurer{}), items)
+	check := func(l, s typed.Fingertree[int, int], msg string) {
+		assertEqual(l.Measure(), s.Measure(), msg)
+		assertEqual(l.Len(), s.Len(), msg)
+		assertEqual(fmt.Sprint(typed.Items(l)), fmt.Sprint(typed.Items(s)), msg)
+		assertEqual(fmt.Sprint(typed.Items(l)), fmt.Sprint(typed.Items(l.Compact())), msg)
+	}
+	for i := 0; i <= 300; i += 11 {
+		ls, ss := lazy.SplitAt(i), strict.SplitAt(i)
+		check(ls[0], ss[0], "Bad strict split")
+		check(ls[1], ss[1], "Bad strict split")
+		check(ls[1].ConcatWith(items[:i%7], ls[0]), ss[1].ConcatWith(items[:i%7], ss[0]), "Bad strict concat")
+	}
+	for lazy.Len() > 1 {
+		lazy, strict = lazy.RemoveFirst().RemoveLast(), strict.RemoveFirst().RemoveLast()
+		check(lazy, strict, "Bad strict remove")
+	}
+	check(lazy, strict, "Bad strict remove")
+}
+
+//testCounting check that items are measured once, when they are
+//added, and that finding and splitting only make O(log n) sums
+func testCounting() {
+	c := typed.Counting[int, int](sumMeasurer{})
+	items := ints(1000)
+	t := typed.FromSlice[int, int](c, items)
+	measures, _ := c.Counts()
+	assertEqual(int64(1000), measures, "Bad measure count for FromSlice")
+	t = t.AddFirst(0).AddLast(1001)
+	measures, _ = c.Counts()
+	assertEqual(int64(1002), measures, "Bad measure count for AddFirst and AddLast")
+	ops := map[string]func(){
+		"Measure":     func() { t.Measure() },
+		"Find":        func() { t.Find(func(m int) bool { return m > 250000 }) },
+		"At":          func() { t.At(777) },
+		"SplitAt":     func() { t.SplitAt(333)[1].Measure() },
+		"RemoveFirst": func() { t.RemoveFirst().RemoveFirst().Measure() },
+		"Concat":      func() { t.Concat(t).Measure() },
+		"Cursor": func() {
+			cur := typed.NewCursorAt(t, 500)
+			cur.Next()
+			cur.Prev()
+			cur.Delete()
+			cur.Close().Measure()
+		},
+	}
+	for name, op := range ops {
+		assertNoMeasures(name, c, op)
+		if _, sums := c.Counts(); sums > 200 {
+			panic(fmt.Sprintf("Too many sums for %s: %d", name, sums))
+		}
+	}
+}
+
+//testChunked check that chunked trees behave like unchunked ones
+func testChunked() {
+	items := ints(100)
+	same := func(plain, chunked typed.Fingertree[int, int], msg string) {
+		assertEqual(plain.Measure(), chunked.Measure(), msg)
+		assertEqual(plain.Len(), chunked.Len(), msg)
+		assertEqual(fmt.Sprint(typed.Items(plain)), fmt.Sprint(typed.Items(chunked)), msg)
+	}
+	for _, k := range []int{1, 3, 8, 200} {
+		plain := typed.FromSlice[int, int](sumMeasurer{}, items)
+		chunked := typed.NewChunked[int, int](sumMeasurer{}, k, items...)
+		same(plain, chunked, "Bad chunked tree")
+		chunked.EachChunk(func(chunk []int) bool {
+			if len(chunk) == 0 || len(chunk) > k {
+				panic(fmt.Sprintf("Bad chunk size %d for k = %d", len(chunk), k))
+			}
+			return true
+		})
+		for n := 0; n <= 5050; n += 97 {
+			p := func(m int) bool { return m > n }
+			ps, cs := plain.Split(p), chunked.Split(p)
+			same(ps[0], cs[0], "Bad chunked split")
+			same(ps[1], cs[1], "Bad chunked split")
+			same(plain.Drop(n/50), chunked.Drop(n/50), "Bad chunked drop")
+			assertEqual(fmt.Sprint(plain.Locate(p)), fmt.Sprint(chunked.Locate(p)), "Bad chunked locate")
+			assertEqual(plain.At(n/50), chunked.At(n/50), "Bad chunked item at index")
+			pl, pp, pr, pm, pok := plain.SplitAt3(p)
+			cl, cp, cr, cm, cok := chunked.SplitAt3(p)
+			same(pl, cl, "Bad chunked SplitAt3")
+			same(pr, cr, "Bad chunked SplitAt3")
+			assertEqual(fmt.Sprint(pp, pm, pok), fmt.Sprint(cp, cm, cok), "Bad chunked SplitAt3")
+			sp := func(l, r int) bool { return l*2 > r+n }
+			pl, pp, pr, pm, pok = plain.Search(sp)
+			cl, cp, cr, cm, cok = chunked.Search(sp)
+			same(pl, cl, "Bad chunked Search")
+			same(pr, cr, "Bad chunked Search")
+			assertEqual(fmt.Sprint(pp, pm, pok), fmt.Sprint(cp, cm, cok), "Bad chunked Search")
+			joined := cs[1].ConcatWith([]int{7, 8, 9}, cs[0])
+			same(ps[1].ConcatWith([]int{7, 8, 9}, ps[0]), joined, "Bad chunked concat")
+			same(ps[1].Concat(cs[0]), cs[1].Concat(ps[0]), "Bad mixed concat")
+		}
+		p, c := plain, typed.Fingertree[int, int](chunked)
+		for i := 0; i < 30; i++ {
+			p, c = p.AddFirst(-i).AddLast(1000+i), c.AddFirst(-i).AddLast(1000+i)
+			same(p, c, "Bad chunked add")
+		}
+		for !p.IsEmpty() {
+			p, c = p.RemoveFirst(), c.RemoveFirst()
+			same(p, c, "Bad chunked remove")
+			p, c = p.RemoveLast(), c.RemoveLast()
+			same(p, c, "Bad chunked remove")
+		}
+		cur := typed.NewCursorAt[int, int](chunked, 10)
+		cur.Delete()
+		same(plain.Take(10).Concat(plain.Drop(11)), cur.Close(), "Bad chunked cursor")
+	}
+}
+
+//text a piece of a rope, it implements typed.SplittableItem[text, int]
+type text string
+
+func (t text) SplitItem(prefix int, p typed.Predicate[int]) (text, text, bool) {
+	n := 0
+	for n < len(t) && !p(prefix+n+1) {
+		n++
+	}
+	return t[:n], t[n:], n > 0
+}
+
+//lengthMeasurer measures text by its length
+type lengthMeasurer struct{}
+
+func (lengthMeasurer) Identity() int          { return 0 }
+func (lengthMeasurer) Measure(t text) int     { return len(t) }
+func (lengthMeasurer) Sum(m1 int, m2 int) int { return m1 + m2 }
+
+//testSplittableItem split ropes at every character offset
+func testSplittableItem() {
+	pieces := []text{"hello ", "there ", "big ", "wide ", "world"}
+	full := "hello there big wide world"
+	str := func(t typed.Fingertree[text, int]) string {
+		result := ""
+		t.Each(func(piece text) bool {
+			result += string(piece)
+			return true
+		})
+		return result
+	}
+	ropes := []typed.Fingertree[text, int]{
+		typed.With[text, int](lengthMeasurer{}, pieces...),
+		typed.NewChunked[text, int](lengthMeasurer{}, 2, pieces...),
+	}
+	for _, rope := range ropes {
+		for offset := 0; offset <= len(full); offset++ {
+			split := rope.Split(func(m int) bool { return m > offset })
+			assertEqual(full[:offset], str(split[0]), "Bad rope split")
+			assertEqual(full[offset:], str(split[1]), "Bad rope split")
+			assertEqual(offset, split[0].Measure(), "Bad rope split measure")
+			assertEqual(full[:offset], str(rope.TakeUntil(func(m int) bool { return m > offset })), "Bad rope take")
+		}
+		assertEqual(2, rope.SplitAt(2)[0].Len(), "Bad rope split at index")
+	}
+}
+
+//testIterators walk trees of many shapes, including lazy and chunked
+//ones, forward and backward from every position
+func testIterators(m *Measurer, t Fingertree) {
+	walk := func(tree typed.Fingertree[int, int], items []int, msg string) {
+		assertEqual(fmt.Sprint(items), fmt.Sprint(slices.Collect(tree.All())), msg+" All")
+		backward := slices.Collect(tree.Backward())
+		slices.Reverse(backward)
+		assertEqual(fmt.Sprint(items), fmt.Sprint(backward), msg+" Backward")
+		for start := 0; start <= len(items); start++ {
+			it := typed.IterAt(tree, start)
+			for i := start; i < len(items); i++ {
+				item, ok := it.Next()
+				assertEqual(true, ok, msg+" Next")
+				assertEqual(items[i], item, msg+" Next")
+			}
+			_, ok := it.Next()
+			assertEqual(false, ok, msg+" Next past end")
+			for i := len(items) - 1; i >= 0; i-- {
+				item, ok := it.Prev()
+				assertEqual(true, ok, msg+" Prev")
+				assertEqual(items[i], item, msg+" Prev")
+			}
+			_, ok = it.Prev()
+			assertEqual(false, ok, msg+" Prev before start")
+			if start < len(items) {
+				it = typed.IterFrom(tree, func(m int) bool { return m > start })
+				item, _ := it.Next()
+				assertEqual(items[start], item, msg+" IterFrom")
+				item, _ = it.Prev()
+				assertEqual(items[start], item, msg+" IterFrom")
+				item, ok = it.Prev()
+				assertEqual(start > 0, ok, msg+" IterFrom")
+			}
+		}
+		it := typed.IterFrom(tree, func(m int) bool { return m > len(items) })
+		item, ok := it.Prev()
+		assertEqual(len(items) > 0, ok, msg+" IterFrom past end")
+		if ok {
+			assertEqual(items[len(items)-1], item, msg+" IterFrom past end")
+		}
+	}
+	for n := 0; n <= 60; n++ {
+		items := ints(n)
+		plain := typed.FromSlice[int, int](countMeasurer{}, items)
+		walk(plain, items, "Bad iterator")
+		walk(typed.NewChunked[int, int](countMeasurer{}, 4, items...), items, "Bad chunked iterator")
+		grown := typed.Empty[int, int](countMeasurer{})
+		for i := n; i > 0; i-- {
+			grown = grown.AddFirst(i)
+		}
+		walk(grown, items, "Bad iterator")
+		if n > 2 {
+			walk(plain.RemoveFirst().RemoveLast(), items[1:n-1], "Bad lazy iterator")
+			walk(plain.SplitAt(n / 2)[1], items[n/2:], "Bad lazy iterator")
+		}
+	}
+	// zip two trees with iterators
+	evens := typed.FromSeq[int, int](countMeasurer{}, func(yield func(int) bool) {
+		for i := 0; i < 100 && yield(i*2); i++ {
+		}
+	})
+	odds := typed.NewChunked[int, int](countMeasurer{}, 3)
+	for i := 0; i < 100; i++ {
+		odds = odds.AddLast(i*2 + 1).(*typed.Chunked[int, int])
+	}
+	ei, oi := typed.NewIterator(evens), typed.NewIterator[int, int](odds)
+	for i := 0; i < 100; i++ {
+		e, _ := ei.Next()
+		o, _ := oi.Next()
+		assertEqual(e+1, o, "Bad zipped iterators")
+	}
+	byIndex := maps.Collect(typed.Enumerate(evens))
+	byHalf := maps.Collect(typed.KeyedBy(evens, func(i int) int { return i / 2 }))
+	for i := 0; i < 100; i++ {
+		assertEqual(i*2, byIndex[i], "Bad Enumerate")
+		assertEqual(i*2, byHalf[i], "Bad KeyedBy")
+	}
+	sum := 0
+	for item := range t.All() {
+		sum += item.(int)
+	}
+	assertEqual(5050, sum, "Bad untyped All")
+	it := IterFrom(t, func(m MeasureValue) bool { return m.(int) > 90 })
+	assertEqual(fmt.Sprint(Items(t.Drop(90))), fmt.Sprint(slices.Collect(it.All())), "Bad untyped IterFrom")
+	assertEqual(fmt.Sprint(Items(t)), fmt.Sprint(Items(FromSeq(m, t.All()))), "Bad untyped FromSeq")
+	assertEqual(37, maps.Collect(Enumerate(t))[36], "Bad untyped Enumerate")
+	assertEqual(42, maps.Collect(KeyedBy(t, func(item TreeItem) interface{} { return item.(int) * 10 }))[420], "Bad untyped KeyedBy")
+}
+
+//testEachWithMeasure check the measurements around each item against
+//running sums, in both directions and when stopping early
+func testEachWithMeasure(t Fingertree) {
+	type visit struct{ item, before, after int }
+	collect := func(each func(typed.MeasuredCode[int, int]) bool, stop int) []visit {
+		var visits []visit
+		each(func(item, before, after int) bool {
+			visits = append(visits, visit{item, before, after})
+			return len(visits) != stop
+		})
+		return visits
+	}
+	check := func(tree typed.Fingertree[int, int], items []int, msg string) {
+		var expected []visit
+		sum := 0
+		for _, item := range items {
+			expected = append(expected, visit{item, sum, sum + item})
+			sum += item
+		}
+		assertEqual(fmt.Sprint(expected), fmt.Sprint(collect(tree.EachWithMeasure, -1)), msg)
+		slices.Reverse(expected)
+		assertEqual(fmt.Sprint(expected), fmt.Sprint(collect(tree.EachReverseWithMeasure, -1)), msg+" in reverse")
+		if len(items) > 3 {
+			assertEqual(3, len(collect(tree.EachWithMeasure, 3)), msg+" stopping early")
+			assertEqual(fmt.Sprint(expected[:3]), fmt.Sprint(collect(tree.EachReverseWithMeasure, 3)), msg+" stopping early")
+		}
+	}
+	for n := 0; n <= 80; n++ {
+		items := ints(n)
+		for _, tree := range shapes[int, int](sumMeasurer{}, 3, items) {
+			check(tree, items, "Bad EachWithMeasure")
+		}
+		plain := typed.FromSlice[int, int](sumMeasurer{}, items)
+		if n > 2 {
+			check(plain.RemoveFirst().RemoveLast(), items[1:n-1], "Bad lazy EachWithMeasure")
+			check(plain.SplitAt(n / 3)[1], items[n/3:], "Bad lazy EachWithMeasure")
+		}
+	}
+	// items are not measured again and each one takes one sum
+	c := typed.Counting[int, int](sumMeasurer{})
+	counted := typed.FromSlice[int, int](c, make([]int, 1000))
+	assertNoMeasures("EachWithMeasure", c, func() {
+		counted.EachWithMeasure(func(item, before, after int) bool { return true })
+	})
+	_, sums := c.Counts()
+	assertEqual(int64(1000), sums, "Bad sum count for EachWithMeasure")
+	index := 0
+	t.EachWithMeasure(func(item TreeItem, before, after MeasureValue) bool {
+		assertEqual(index, before, "Bad untyped EachWithMeasure")
+		assertEqual(index+1, after, "Bad untyped EachWithMeasure")
+		index++
+		return true
+	})
+	assertEqual(t.Len(), index, "Bad untyped EachWithMeasure")
+}
+
+//testRanges compare EachBetween and RangeMeasure with the trees from
+//TakeUntil and DropUntil
+func testRanges(t Fingertree) {
+	items := ints(120)
+	trees := append(shapes[int, int](sumMeasurer{}, 7, items), typed.NewChunked[int, int](sumMeasurer{}, 1, items...))
+	for _, tree := range trees {
+		for a := 0; a <= 7500; a += 431 {
+			for b := 0; b <= 7500; b += 389 {
+				from := func(m int) bool { return m > a }
+				to := func(m int) bool { return m > b }
+				expected := tree.TakeUntil(to).DropUntil(from)
+				var got []int
+				tree.EachBetween(from, to, func(item int) bool {
+					got = append(got, item)
+					return true
+				})
+				assertEqual(fmt.Sprint(typed.Items(expected)), fmt.Sprint(got), "Bad EachBetween")
+				assertEqual(expected.Measure(), tree.RangeMeasure(from, to), "Bad RangeMeasure")
+				if len(got) > 1 {
+					n := 0
+					tree.EachBetween(from, to, func(item int) bool {
+						n++
+						return false
+					})
+					assertEqual(1, n, "Bad EachBetween stopping early")
+				}
+			}
+		}
+	}
+	c := typed.Counting[int, int](sumMeasurer{})
+	counted := typed.FromSlice[int, int](c, make([]int, 10000))
+	assertNoMeasures("RangeMeasure", c, func() {
+		counted.RangeMeasure(func(m int) bool { return m > 0 }, func(m int) bool { return m > 0 })
+	})
+	c = typed.Counting[int, int](countMeasurer{})
+	counts := typed.FromSlice[int, int](c, make([]int, 10000))
+	for lo := 0; lo <= 10000; lo += 1237 {
+		for hi := 0; hi <= 10000; hi += 1111 {
+			c.Reset()
+			from := func(m int) bool { return m > lo }
+			to := func(m int) bool { return m > hi }
+			assertEqual(max(0, hi-lo), counts.RangeMeasure(from, to), "Bad RangeMeasure by count")
+			if _, sums := c.Counts(); sums > 200 {
+				panic(fmt.Sprintf("Too many sums for RangeMeasure: %d", sums))
+			}
+		}
+	}
+	assertEqual(55, t.RangeMeasure(func(m MeasureValue) bool { return m.(int) > 45 }, func(m MeasureValue) bool { return m.(int) > 100 }),
+		"Bad untyped RangeMeasure")
+	sum := 0
+	t.EachBetween(func(m MeasureValue) bool { return m.(int) > 45 }, func(m MeasureValue) bool { return m.(int) > 55 }, func(item TreeItem) bool {
+		sum += item.(int)
+		return true
+	})
+	assertEqual(505, sum, "Bad untyped EachBetween")
+}
+
+//testMap remeasure and map trees of many shapes, including lazy and
+//chunked ones
+func testMap(m *Measurer, t Fingertree) {
+	check := func(tree typed.Fingertree[int, int], msg string) {
+		c := typed.Counting[int, int](sumMeasurer{})
+		summed := typed.Remeasure[int, int, int](tree, c)
+		measures, _ := c.Counts()
+		assertEqual(int64(tree.Len()), measures, msg+" measure count")
+		assertEqual(fmt.Sprint(typed.Items(tree)), fmt.Sprint(typed.Items(summed)), msg)
+		total := 0
+		for i, item := range typed.Items(tree) {
+			assertEqual(total, summed.SplitAt(i)[0].Measure(), msg)
+			total += item
+		}
+		assertEqual(total, summed.Measure(), msg)
+		texts := typed.Map[int, int, text, int](tree, func(i int) text { return text(strconv.Itoa(i)) }, lengthMeasurer{})
+		str := ""
+		tree.Each(func(i int) bool {
+			str += strconv.Itoa(i)
+			return true
+		})
+		assertEqual(len(str), texts.Measure(), msg+" map")
+		assertEqual(tree.Len(), texts.Len(), msg+" map")
+	}
+	for n := 0; n <= 80; n++ {
+		items := ints(n)
+		for _, tree := range shapes[int, int](countMeasurer{}, 4, items) {
+			check(tree, "Bad Remeasure")
+		}
+		plain := typed.FromSlice[int, int](countMeasurer{}, items)
+		grown := typed.Empty[int, int](countMeasurer{})
+		for _, item := range items {
+			grown = grown.AddFirst(item)
+		}
+		check(grown, "Bad Remeasure")
+		if n > 2 {
+			check(plain.RemoveFirst().RemoveLast(), "Bad lazy Remeasure")
+			check(plain.SplitAt(n / 3)[1].Concat(plain.SplitAt(n / 2)[0]), "Bad lazy Remeasure")
+		}
+		chunked := typed.NewChunked[int, int](countMeasurer{}, 4, items...).Concat(plain.Take(n / 2))
+		check(chunked, "Bad chunked Remeasure")
+		var sizes, mappedSizes []int
+		chunked.(*typed.Chunked[int, int]).EachChunk(func(items []int) bool {
+			sizes = append(sizes, len(items))
+			return true
+		})
+		typed.Remeasure[int, int, int](chunked, sumMeasurer{}).(*typed.Chunked[int, int]).EachChunk(func(items []int) bool {
+			mappedSizes = append(mappedSizes, len(items))
+			return true
+		})
+		assertEqual(fmt.Sprint(sizes), fmt.Sprint(mappedSizes), "Bad chunked Remeasure chunks")
+	}
+	sums := NewMeasurer(
+		func() MeasureValue { return 0 },
+		func(i TreeItem) MeasureValue { return i },
+		func(a, b MeasureValue) MeasureValue { return a.(int) + b.(int) })
+	assertEqual(5050, Remeasure(t, sums).Measure(), "Bad untyped Remeasure")
+	doubled := Map(t, func(i TreeItem) TreeItem { return i.(int) * 2 }, sums)
+	assertEqual(10100, doubled.Measure(), "Bad untyped Map")
+	assertEqual(200, doubled.PeekLast(), "Bad untyped Map")
+	assertEqual(100, Remeasure(doubled, m).Measure(), "Bad untyped Remeasure")
+}
+
+//testFilter filter, partition and group trees of many shapes and
+//check that the items keep their order and measurements
+func testFilter(t Fingertree) {
+	for n := 0; n <= 100; n += 3 {
+		items := ints(n)
+		for _, tree := range shapes[int, int](sumMeasurer{}, 5, items) {
+			_, chunked := tree.(*typed.Chunked[int, int])
+			check := func(result typed.Fingertree[int, int], expected []int, msg string) {
+				_, isChunked := result.(*typed.Chunked[int, int])
+				assertEqual(chunked, isChunked, msg+" kind")
+				assertEqual(fmt.Sprint(expected), fmt.Sprint(typed.Items(result)), msg)
+				sum := 0
+				for i, item := range expected {
+					assertEqual(sum, result.SplitAt(i)[0].Measure(), msg)
+					sum += item
+				}
+				assertEqual(sum, result.Measure(), msg)
+			}
+			var evens, odds []int
+			groups := map[int][]int{}
+			for _, item := range items {
+				if item%2 == 0 {
+					evens = append(evens, item)
+				} else {
+					odds = append(odds, item)
+				}
+				groups[item%3] = append(groups[item%3], item)
+			}
+			even := func(i int) bool { return i%2 == 0 }
+			check(typed.Filter(tree, even), evens, "Bad Filter")
+			check(typed.Filter(tree, func(int) bool { return false }), nil, "Bad empty Filter")
+			yes, no := typed.Partition(tree, even)
+			check(yes, evens, "Bad Partition")
+			check(no, odds, "Bad Partition")
+			grouped := typed.GroupBy(tree, func(i int) int { return i % 3 })
+			assertEqual(len(groups), len(grouped), "Bad GroupBy keys")
+			for k, expected := range groups {
+				check(grouped[k], expected, "Bad GroupBy")
+			}
+		}
+	}
+	c := typed.Counting[int, int](sumMeasurer{})
+	counted := typed.FromSlice[int, int](c, make([]int, 1000))
+	assertNoMeasures("GroupBy", c, func() { typed.GroupBy(counted, func(i int) int { return i % 7 }) })
+	odd := func(item TreeItem) bool { return item.(int)%2 == 1 }
+	assertEqual(50, Filter(t, odd).Measure(), "Bad untyped Filter")
+	yes, no := Partition(t, odd)
+	assertEqual(1, yes.PeekFirst(), "Bad untyped Partition")
+	assertEqual(100, no.PeekLast(), "Bad untyped Partition")
+	grouped := GroupBy(t, func(item TreeItem) interface{} { return item.(int) > 90 })
+	assertEqual(10, grouped[true].Len(), "Bad untyped GroupBy")
+	assertEqual(90, grouped[false].Len(), "Bad untyped GroupBy")
+}
+
+//concatMeasurer measures strings by concatenating them, which depends
+//on their order
+type concatMeasurer struct{}
+
+func (concatMeasurer) Identity() string                { return "" }
+func (concatMeasurer) Measure(s string) string         { return s }
+func (concatMeasurer) Sum(m1 string, m2 string) string { return m1 + m2 }
+
+//testReverse reverse trees of many shapes and check their measurements
+//with a measurer that is not commutative
+func testReverse(t Fingertree) {
+	for n := 0; n <= 100; n += 3 {
+		items := make([]string, n)
+		for i := range items {
+			items[i] = string(rune('a' + i%26))
+		}
+		backwards := slices.Clone(items)
+		slices.Reverse(backwards)
+		trees := append(shapes[string, string](concatMeasurer{}, 4, items), typed.FromSlice(typed.Strict[string, string](concatMeasurer{}), items))
+		for _, tree := range trees {
+			reversed := typed.Reverse(tree)
+			_, chunked := tree.(*typed.Chunked[string, string])
+			_, isChunked := reversed.(*typed.Chunked[string, string])
+			assertEqual(chunked, isChunked, "Bad Reverse kind")
+			assertEqual(fmt.Sprint(backwards), fmt.Sprint(typed.Items(reversed)), "Bad Reverse")
+			assertEqual(strings.Join(items, ""), reversed.Measure(), "Bad Reverse measure")
+			for i := 0; i <= n; i++ {
+				assertEqual(strings.Join(items[n-i:], ""), reversed.SplitAt(i)[0].Measure(), "Bad Reverse prefix")
+			}
+			added := reversed.AddFirst("<").AddLast(">")
+			assertEqual(">"+strings.Join(items, "")+"<", added.Measure(), "Bad Reverse AddFirst")
+			again := typed.Reverse(reversed)
+			assertEqual(fmt.Sprint(items), fmt.Sprint(typed.Items(again)), "Bad double Reverse")
+			assertEqual("<"+strings.Join(items, "")+">", again.AddFirst("<").AddLast(">").Measure(), "Bad double Reverse measurer")
+			assertEqual(strings.Join(items, "")+strings.Join(items, ""), again.Concat(tree).Measure(), "Bad double Reverse concat")
+			assertEqual(strings.Join(items, "")+strings.Join(items, ""), reversed.Concat(reversed).Measure(), "Bad Reverse concat")
+		}
+	}
+	c := typed.Counting[int, int](sumMeasurer{})
+	counted := typed.FromSlice[int, int](c, make([]int, 1000))
+	counted.Measure()
+	assertNoMeasures("Reverse", c, func() {
+		reversed := typed.Reverse(counted)
+		assertEqual(counted.Measure(), reversed.Measure(), "Bad Reverse measure")
+		reversed.Compact()
+	})
+	_, sums := c.Counts()
+	assertEqual(int64(0), sums, "Bad sum count for Reverse")
+	assertEqual(100, Reverse(t).PeekFirst(), "Bad untyped Reverse")
+	assertEqual(1, Reverse(t).At(99), "Bad untyped Reverse")
+	dual := Dual(typed.NewMeasurer(func() MeasureValue { return "" }, func(i TreeItem) MeasureValue { return i }, func(a, b MeasureValue) MeasureValue { return a.(string) + b.(string) }))
+	assertEqual("cba", WithMeasurerOf(dual, "a", "b", "c").Measure(), "Bad Dual")
 }
 
 func assertSplitRange(start, mid, end int, t Fingertree, pred Predicate) {
@@ -125,6 +897,37 @@ func assertRange(start, end int, t Fingertree) {
 	}
 }
 
+//ints return the ints from 1 to n
+func ints(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i + 1
+	}
+	return items
+}
+
+//shapes return trees of items made in different ways: built from a
+//slice, chunked by k and, with more than two items, with delayed
+//middles from RemoveFirst and from Split and Concat
+func shapes[T, M any](m typed.MeasurerOf[T, M], k int, items []T) []typed.Fingertree[T, M] {
+	n := len(items)
+	plain := typed.FromSlice(m, items)
+	trees := []typed.Fingertree[T, M]{plain, typed.NewChunked(m, k, items...)}
+	if n > 2 {
+		trees = append(trees, plain.RemoveFirst().AddFirst(items[0]), plain.SplitAt(n / 2)[0].Concat(plain.SplitAt(n / 2)[1]))
+	}
+	return trees
+}
+
+//assertNoMeasures run op and check that it does not measure any items
+//with c
+func assertNoMeasures[T, M any](name string, c *typed.CountingMeasurer[T, M], op func()) {
+	c.Reset()
+	op()
+	measures, _ := c.Counts()
+	assertEqual(int64(0), measures, "Bad measure count for "+name)
+}
+
 func assertEqual(expected interface{}, got interface{}, msg string) {
 	if expected != got {
 		panic(fmt.Sprintf("%s: expected %v but got %v", msg, expected, got))
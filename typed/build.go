@@ -0,0 +1,118 @@
+package typed
+
+import "sync"
+
+// parallelMinimum the fewest elems worth handing to a goroutine when
+// building a tree
+const parallelMinimum = 4096
+
+// builder builds a tree from a stream of elems. It makes the same tree
+// fromArray makes from a slice of them, without holding the elems:
+// it keeps the first three for the left digit and groups the ones
+// after them into nodes for the next level as soon as there are
+// enough left over for the right digit and the last nodes, because
+// nodes groups from the front.
+type builder[T, M any] struct {
+	measurer MeasurerOf[T, M]
+	// the first three elems, then the elems that are not in nodes yet
+	elems []elem[T, M]
+	// the builder for the middle tree, once there are nodes
+	next *builder[T, M]
+}
+
+// BuildParallel makes a balanced tree for items like FromSlice, using
+// up to workers goroutines to measure the items and make the nodes.
+// m's Measure and Sum must be safe for concurrent use. If workers < 1,
+// BuildParallel uses GOMAXPROCS goroutines.
+func BuildParallel[T, M any](m MeasurerOf[T, M], items []T, workers int) Fingertree[T, M] {
+	workers = poolSize(workers)
+	xs := make([]elem[T, M], len(items))
+	parallelRange(len(items), workers, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			xs[i] = leaf(m, items[i])
+		}
+	})
+	return buildParallel(m, xs, workers)
+}
+
+// BuildFromChannel makes a balanced tree for the items received from
+// ch until it is closed, measuring them and making nodes as they
+// arrive. It makes the same tree as FromSlice.
+func BuildFromChannel[T, M any](m MeasurerOf[T, M], ch <-chan T) Fingertree[T, M] {
+	return FromSeq(m, func(yield func(T) bool) {
+		for item := range ch {
+			if !yield(item) {
+				return
+			}
+		}
+	})
+}
+
+// buildParallel is fromArray with the nodes for each level made in
+// parallel
+func buildParallel[T, M any](m MeasurerOf[T, M], xs []elem[T, M], workers int) splittable[T, M] {
+	n := len(xs)
+	if n <= 8 || workers == 1 || n < 2*parallelMinimum {
+		return fromArray(m, xs)
+	}
+	return newDeep(m,
+		newDigit(m, xs[:3:3]),
+		buildParallel(m, parallelNodes(m, xs[3:n-3], workers), workers),
+		newDigit(m, xs[n-3:]))
+}
+
+// parallelNodes make the nodes that nodes makes for xs, which has more
+// than four elems. nodes groups them by threes from the front while
+// more than four are left, so the groups before the last two to four
+// elems can be made independently.
+func parallelNodes[T, M any](m MeasurerOf[T, M], xs []elem[T, M], workers int) []elem[T, M] {
+	groups := (len(xs) - 2) / 3
+	result := make([]elem[T, M], groups, groups+2)
+	parallelRange(groups, workers, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			result[i] = newNode(m, xs[i*3:i*3+3:i*3+3])
+		}
+	})
+	return nodes(m, xs[groups*3:], result)
+}
+
+// parallelRange run f on up to workers ranges that cover 0 to n
+func parallelRange(n, workers int, f func(lo, hi int)) {
+	size := max(parallelMinimum, (n+workers-1)/workers)
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += size {
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			f(lo, hi)
+		}(lo, min(n, lo+size))
+	}
+	wg.Wait()
+}
+
+// add append e to the elems, once there are eight after the first
+// three the first three of those can go into a node
+func (b *builder[T, M]) add(e elem[T, M]) {
+	b.elems = append(b.elems, e)
+	if len(b.elems) == 11 {
+		if b.next == nil {
+			b.next = &builder[T, M]{measurer: b.measurer}
+		}
+		b.next.add(newNode(b.measurer, b.elems[3:6]))
+		b.elems = append(b.elems[:3], b.elems[6:]...)
+	}
+}
+
+// finish return the tree, the last three elems make the right digit
+// and the rest go into nodes
+func (b *builder[T, M]) finish() splittable[T, M] {
+	m := b.measurer
+	if b.next == nil {
+		return fromArray(m, b.elems)
+	}
+	n := len(b.elems)
+	for _, node := range nodes(m, b.elems[3:n-3], nil) {
+		b.next.add(node)
+	}
+	return newDeep(m, newDigit(m, b.elems[:3]), b.next.finish(), newDigit(m, b.elems[n-3:]))
+}
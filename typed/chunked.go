@@ -0,0 +1,428 @@
+package typed
+
+import "iter"
+
+// Chunked a Fingertree that packs up to k items into each leaf, for
+// long sequences of small items. The leaves are slices with their
+// combined measurement in a tree of chunks, so Each streams slices and
+// a tree of n items has about n/k leaves, while Split, Find and Concat
+// stay O(log n). Split, Find and Search scan the chunk they land in
+// linearly and measure its items again, as do RemoveFirst and
+// RemoveLast for the chunk they shrink, so k should stay small when
+// Measure is expensive.
+//
+// Concatenating a Chunked tree with an unchunked tree copies the
+// other tree's items, and NewCursor copies a Chunked tree's items into
+// an unchunked tree.
+type Chunked[T, M any] struct {
+	measurer      MeasurerOf[T, M]
+	chunkMeasurer MeasurerOf[*chunk[T, M], chunkMeasure[M]]
+	k             int
+	tree          Fingertree[*chunk[T, M], chunkMeasure[M]]
+}
+
+// chunk a leaf of a Chunked tree, it has at least one item and it is
+// not changed after it is made
+type chunk[T, M any] struct {
+	items       []T
+	measurement M
+}
+
+// chunkMeasure the number of items in some chunks and their measurement
+type chunkMeasure[M any] struct {
+	count   int
+	measure M
+}
+
+// chunkMeasurer measures chunks with the measurements they carry
+type chunkMeasurer[T, M any] struct {
+	measurer MeasurerOf[T, M]
+}
+
+// chunkSplit a Chunked tree split inside a chunk
+type chunkSplit[T, M any] struct {
+	// the chunks before chunk
+	left Fingertree[*chunk[T, M], chunkMeasure[M]]
+	// the chunk holding the split point
+	chunk *chunk[T, M]
+	// the index of the split point in chunk
+	index int
+	// the measurement of the items in chunk before index
+	partial M
+	// the chunks after chunk
+	right Fingertree[*chunk[T, M], chunkMeasure[M]]
+	// the position of the split point
+	before pos[M]
+}
+
+func (c chunkMeasurer[T, M]) Identity() chunkMeasure[M] {
+	return chunkMeasure[M]{0, c.measurer.Identity()}
+}
+func (c chunkMeasurer[T, M]) Measure(ch *chunk[T, M]) chunkMeasure[M] {
+	return chunkMeasure[M]{len(ch.items), ch.measurement}
+}
+func (c chunkMeasurer[T, M]) Sum(a, b chunkMeasure[M]) chunkMeasure[M] {
+	return chunkMeasure[M]{a.count + b.count, c.measurer.Sum(a.measure, b.measure)}
+}
+
+// NewChunked make a Chunked tree for items with up to k items in each
+// leaf. Trees made with a Strict measurer are strict.
+func NewChunked[T, M any](m MeasurerOf[T, M], k int, items ...T) *Chunked[T, M] {
+	if k < 1 {
+		panic("Chunk size must be at least 1")
+	}
+	var cm MeasurerOf[*chunk[T, M], chunkMeasure[M]] = chunkMeasurer[T, M]{m}
+	if isStrict(m) {
+		cm = Strict(cm)
+	}
+	c := &Chunked[T, M]{m, cm, k, Empty(cm)}
+	return c.fromItems(items)
+}
+
+// with return a Chunked tree like c with different chunks
+func (c *Chunked[T, M]) with(tree Fingertree[*chunk[T, M], chunkMeasure[M]]) *Chunked[T, M] {
+	return &Chunked[T, M]{c.measurer, c.chunkMeasurer, c.k, tree}
+}
+
+// fromItems return a Chunked tree like c for a copy of items
+func (c *Chunked[T, M]) fromItems(items []T) *Chunked[T, M] {
+	items = append([]T(nil), items...)
+	chunks := make([]*chunk[T, M], 0, (len(items)+c.k-1)/c.k)
+	for len(items) > 0 {
+		n := c.k
+		if n > len(items) {
+			n = len(items)
+		}
+		chunks = append(chunks, c.newChunk(items[:n:n]))
+		items = items[n:]
+	}
+	return c.with(FromSlice(c.chunkMeasurer, chunks))
+}
+
+// newChunk make a chunk that keeps items, which must not be empty
+func (c *Chunked[T, M]) newChunk(items []T) *chunk[T, M] {
+	m := c.measurer
+	measurement := m.Measure(items[0])
+	for _, item := range items[1:] {
+		measurement = m.Sum(measurement, m.Measure(item))
+	}
+	return &chunk[T, M]{items, measurement}
+}
+
+// join return a Chunked tree with c's chunks followed by right's,
+// merging the chunks where they meet if they fit in one
+func (c *Chunked[T, M]) join(right Fingertree[*chunk[T, M], chunkMeasure[M]]) *Chunked[T, M] {
+	l, lok := c.tree.PeekLastOK()
+	r, rok := right.PeekFirstOK()
+	if lok && rok && len(l.items)+len(r.items) <= c.k {
+		items := make([]T, 0, len(l.items)+len(r.items))
+		merged := &chunk[T, M]{append(append(items, l.items...), r.items...),
+			c.measurer.Sum(l.measurement, r.measurement)}
+		return c.with(c.tree.RemoveLast().ConcatWith([]*chunk[T, M]{merged}, right.RemoveFirst()))
+	}
+	return c.with(c.tree.Concat(right))
+}
+
+// chunkPredicate adapt a seeker starting at i to chunk measurements
+func (c *Chunked[T, M]) chunkPredicate(s seeker[M], i pos[M]) Predicate[chunkMeasure[M]] {
+	return func(cm chunkMeasure[M]) bool { return s(i.add(c.measurer, cm.count, cm.measure)) }
+}
+
+// split find the first item that satisfies s and the chunks around it
+func (c *Chunked[T, M]) split(s seeker[M]) (chunkSplit[T, M], bool) {
+	m := c.measurer
+	left, ch, right, prefix, ok := c.tree.SplitAt3(c.chunkPredicate(s, start(m)))
+	if !ok {
+		return chunkSplit[T, M]{}, false
+	}
+	i := pos[M]{prefix.count, prefix.measure}
+	partial := m.Identity()
+	n := 0
+	for ; n < len(ch.items)-1; n++ {
+		measurement := m.Measure(ch.items[n])
+		next := i.add(m, 1, measurement)
+		if s(next) {
+			break
+		}
+		if n == 0 {
+			partial = measurement
+		} else {
+			partial = m.Sum(partial, measurement)
+		}
+		i = next
+	}
+	return chunkSplit[T, M]{left, ch, n, partial, right, i}, true
+}
+
+// before return the items before the split point
+func (c *Chunked[T, M]) before(sp chunkSplit[T, M]) *Chunked[T, M] {
+	if sp.index == 0 {
+		return c.with(sp.left)
+	}
+	return c.with(sp.left.AddLast(&chunk[T, M]{sp.chunk.items[:sp.index:sp.index], sp.partial}))
+}
+
+// after return the items in sp's chunk from index on and the chunks after it
+func (c *Chunked[T, M]) after(sp chunkSplit[T, M], index int) *Chunked[T, M] {
+	if index == len(sp.chunk.items) {
+		return c.with(sp.right)
+	}
+	return c.with(sp.right.AddFirst(c.newChunk(sp.chunk.items[index:])))
+}
+
+func (c *Chunked[T, M]) splitTrees(s seeker[M]) []Fingertree[T, M] {
+	sp, ok := c.split(s)
+	if !ok {
+		return []Fingertree[T, M]{c, c.with(Empty(c.chunkMeasurer))}
+	}
+	return []Fingertree[T, M]{c.before(sp), c.after(sp, sp.index)}
+}
+
+func (c *Chunked[T, M]) find(s seeker[M], i pos[M], l T, hasL bool) FindResult[T, M] {
+	m := c.measurer
+	r := c.tree.Locate(c.chunkPredicate(s, i))
+	if r.HasBefore {
+		l, hasL = r.Before.items[len(r.Before.items)-1], true
+	}
+	i = i.add(m, r.Prefix.count, r.Prefix.measure)
+	if !r.HasAt {
+		return notFound[T](l, hasL, i)
+	}
+	for _, item := range r.At.items {
+		next := i.add(m, 1, m.Measure(item))
+		if s(next) {
+			return FindResult[T, M]{l, item, hasL, true, i.count, i.measure, next.measure}
+		}
+		l, hasL, i = item, true, next
+	}
+	return notFound[T](l, hasL, i)
+}
+
+// plain return an unchunked tree with c's items
+func (c *Chunked[T, M]) plain() splittable[T, M] {
+	return fromArray(c.measurer, leaves(c.measurer, Items[T, M](c)))
+}
+
+// EachChunk execute f on the items of each chunk until it returns
+// false or all the chunks have been processed. Returns whether all of
+// the chunks were processed. f must not change the slices.
+func (c *Chunked[T, M]) EachChunk(f func([]T) bool) bool {
+	return c.tree.Each(func(ch *chunk[T, M]) bool { return f(ch.items) })
+}
+
+func (c *Chunked[T, M]) All() iter.Seq[T]      { return seq(c.Each) }
+func (c *Chunked[T, M]) Backward() iter.Seq[T] { return seq(c.EachReverse) }
+
+// EachWithMeasure measures the items of each chunk again, starting
+// from the measurement before the chunk
+func (c *Chunked[T, M]) EachWithMeasure(f MeasuredCode[T, M]) bool {
+	m := c.measurer
+	return c.tree.EachWithMeasure(func(ch *chunk[T, M], before, _ chunkMeasure[M]) bool {
+		prefix := before.measure
+		for _, item := range ch.items {
+			after := m.Sum(prefix, m.Measure(item))
+			if !f(item, prefix, after) {
+				return false
+			}
+			prefix = after
+		}
+		return true
+	})
+}
+
+// EachReverseWithMeasure sums the measurements before the items of
+// each chunk first, so the items can go in reverse
+func (c *Chunked[T, M]) EachReverseWithMeasure(f MeasuredCode[T, M]) bool {
+	m := c.measurer
+	var prefixes []M
+	return c.tree.EachReverseWithMeasure(func(ch *chunk[T, M], before, after chunkMeasure[M]) bool {
+		prefixes = append(prefixes[:0], before.measure)
+		for _, item := range ch.items[:len(ch.items)-1] {
+			prefixes = append(prefixes, m.Sum(prefixes[len(prefixes)-1], m.Measure(item)))
+		}
+		prefixes = append(prefixes, after.measure)
+		for i := len(ch.items) - 1; i >= 0; i-- {
+			if !f(ch.items[i], prefixes[i], prefixes[i+1]) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (c *Chunked[T, M]) Measure() M    { return c.tree.Measure().measure }
+func (c *Chunked[T, M]) Len() int      { return c.tree.Measure().count }
+func (c *Chunked[T, M]) IsEmpty() bool { return c.tree.IsEmpty() }
+func (c *Chunked[T, M]) Each(f Code[T]) bool {
+	return c.tree.Each(func(ch *chunk[T, M]) bool {
+		for _, item := range ch.items {
+			if !f(item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+func (c *Chunked[T, M]) EachReverse(f Code[T]) bool {
+	return c.tree.EachReverse(func(ch *chunk[T, M]) bool {
+		for i := len(ch.items) - 1; i >= 0; i-- {
+			if !f(ch.items[i]) {
+				return false
+			}
+		}
+		return true
+	})
+}
+func (c *Chunked[T, M]) first() T     { return c.PeekFirst() }
+func (c *Chunked[T, M]) last() T      { return c.PeekLast() }
+func (c *Chunked[T, M]) PeekFirst() T { item, _ := c.PeekFirstOK(); return item }
+func (c *Chunked[T, M]) PeekLast() T  { item, _ := c.PeekLastOK(); return item }
+func (c *Chunked[T, M]) PeekFirstOK() (item T, ok bool) {
+	if ch, ok := c.tree.PeekFirstOK(); ok {
+		return ch.items[0], true
+	}
+	return
+}
+func (c *Chunked[T, M]) PeekLastOK() (item T, ok bool) {
+	if ch, ok := c.tree.PeekLastOK(); ok {
+		return ch.items[len(ch.items)-1], true
+	}
+	return
+}
+func (c *Chunked[T, M]) AddFirst(i T) Fingertree[T, M] {
+	m := c.measurer
+	if ch, ok := c.tree.PeekFirstOK(); ok && len(ch.items) < c.k {
+		items := append(append(make([]T, 0, len(ch.items)+1), i), ch.items...)
+		return c.with(c.tree.RemoveFirst().AddFirst(&chunk[T, M]{items, m.Sum(m.Measure(i), ch.measurement)}))
+	}
+	return c.with(c.tree.AddFirst(c.newChunk([]T{i})))
+}
+func (c *Chunked[T, M]) AddLast(i T) Fingertree[T, M] {
+	m := c.measurer
+	if ch, ok := c.tree.PeekLastOK(); ok && len(ch.items) < c.k {
+		items := append(append(make([]T, 0, len(ch.items)+1), ch.items...), i)
+		return c.with(c.tree.RemoveLast().AddLast(&chunk[T, M]{items, m.Sum(ch.measurement, m.Measure(i))}))
+	}
+	return c.with(c.tree.AddLast(c.newChunk([]T{i})))
+}
+func (c *Chunked[T, M]) RemoveFirst() Fingertree[T, M] {
+	ch, ok := c.tree.PeekFirstOK()
+	if !ok {
+		return c
+	}
+	rest := c.tree.RemoveFirst()
+	if len(ch.items) > 1 {
+		rest = rest.AddFirst(c.newChunk(ch.items[1:]))
+	}
+	return c.with(rest)
+}
+func (c *Chunked[T, M]) RemoveLast() Fingertree[T, M] {
+	ch, ok := c.tree.PeekLastOK()
+	if !ok {
+		return c
+	}
+	rest := c.tree.RemoveLast()
+	if n := len(ch.items) - 1; n > 0 {
+		rest = rest.AddLast(c.newChunk(ch.items[:n:n]))
+	}
+	return c.with(rest)
+}
+func (c *Chunked[T, M]) Concat(other Fingertree[T, M]) Fingertree[T, M] {
+	if o, ok := other.(*Chunked[T, M]); ok {
+		return c.join(o.tree)
+	}
+	return c.AddAllLast(Items(other)...)
+}
+func (c *Chunked[T, M]) AddAllFirst(items ...T) Fingertree[T, M] {
+	return c.fromItems(items).join(c.tree)
+}
+func (c *Chunked[T, M]) AddAllLast(items ...T) Fingertree[T, M] {
+	return c.join(c.fromItems(items).tree)
+}
+func (c *Chunked[T, M]) ConcatWith(items []T, tree Fingertree[T, M]) Fingertree[T, M] {
+	return c.AddAllLast(items...).Concat(tree)
+}
+func (c *Chunked[T, M]) Split(p Predicate[M]) []Fingertree[T, M] {
+	sp, ok := c.split(measured(p))
+	if !ok {
+		return []Fingertree[T, M]{c, c.with(Empty(c.chunkMeasurer))}
+	}
+	if item, ok := any(sp.chunk.items[sp.index]).(SplittableItem[T, M]); ok {
+		if left, right, ok := item.SplitItem(sp.before.measure, p); ok {
+			return []Fingertree[T, M]{c.before(sp).AddLast(left), c.after(sp, sp.index+1).AddFirst(right)}
+		}
+	}
+	return []Fingertree[T, M]{c.before(sp), c.after(sp, sp.index)}
+}
+func (c *Chunked[T, M]) SplitAt(i int) []Fingertree[T, M] {
+	return c.splitTrees(counted[M](i))
+}
+func (c *Chunked[T, M]) SplitAt3(p Predicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	var none T
+
+	sp, ok := c.split(measured(p))
+	if !ok {
+		return c, none, c.with(Empty(c.chunkMeasurer)), c.Measure(), false
+	}
+	return c.before(sp), sp.chunk.items[sp.index], c.after(sp, sp.index+1), sp.before.measure, true
+}
+func (c *Chunked[T, M]) Search(p SearchPredicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	var none T
+
+	m := c.measurer
+	left, ch, right, prefix, ok := c.tree.Search(func(l, r chunkMeasure[M]) bool {
+		return p(l.measure, r.measure)
+	})
+	if !ok {
+		return c, none, c.with(Empty(c.chunkMeasurer)), c.Measure(), false
+	}
+	measurements := make([]M, len(ch.items))
+	for n, item := range ch.items {
+		measurements[n] = m.Measure(item)
+	}
+	suffixes := make([]M, len(ch.items))
+	suffixes[len(ch.items)-1] = right.Measure().measure
+	for n := len(ch.items) - 2; n >= 0; n-- {
+		suffixes[n] = m.Sum(measurements[n+1], suffixes[n+1])
+	}
+	sp := chunkSplit[T, M]{left, ch, 0, m.Identity(), right, pos[M]{prefix.count, prefix.measure}}
+	for ; sp.index < len(ch.items)-1; sp.index++ {
+		next := sp.before.add(m, 1, measurements[sp.index])
+		if p(next.measure, suffixes[sp.index]) {
+			break
+		}
+		if sp.index == 0 {
+			sp.partial = measurements[0]
+		} else {
+			sp.partial = m.Sum(sp.partial, measurements[sp.index])
+		}
+		sp.before = next
+	}
+	return c.before(sp), ch.items[sp.index], c.after(sp, sp.index+1), sp.before.measure, true
+}
+func (c *Chunked[T, M]) TakeUntil(p Predicate[M]) Fingertree[T, M] { return c.Split(p)[0] }
+func (c *Chunked[T, M]) DropUntil(p Predicate[M]) Fingertree[T, M] { return c.Split(p)[1] }
+func (c *Chunked[T, M]) Take(n int) Fingertree[T, M]               { return c.SplitAt(n)[0] }
+func (c *Chunked[T, M]) Drop(n int) Fingertree[T, M]               { return c.SplitAt(n)[1] }
+func (c *Chunked[T, M]) EachBetween(from, to Predicate[M], f Code[T]) bool {
+	return c.eachBetween(from, to, f)
+}
+func (c *Chunked[T, M]) RangeMeasure(from, to Predicate[M]) M {
+	return c.rangeMeasure(from, to)
+}
+func (c *Chunked[T, M]) Find(p Predicate[M]) []T {
+	r := c.Locate(p)
+	return []T{r.Before, r.At}
+}
+func (c *Chunked[T, M]) Locate(p Predicate[M]) FindResult[T, M] {
+	var none T
+
+	return c.find(measured(p), start(c.measurer), none, false)
+}
+func (c *Chunked[T, M]) At(i int) (item T) {
+	if 0 <= i && i < c.Len() {
+		item = c.find(counted[M](i), start(c.measurer), item, false).At
+	}
+	return
+}
+func (c *Chunked[T, M]) Compact() Fingertree[T, M] { return c.with(c.tree.Compact()) }
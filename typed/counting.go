@@ -0,0 +1,42 @@
+package typed
+
+import "sync/atomic"
+
+// CountingMeasurer a MeasurerOf that counts the calls to another
+// measurer's Measure and Sum, use it to see how much measuring an
+// operation does. Counting is safe for concurrent use. To make a
+// strict counting measurer, wrap the CountingMeasurer with Strict.
+type CountingMeasurer[T, M any] struct {
+	measurer MeasurerOf[T, M]
+	measures int64
+	sums     int64
+}
+
+// Counting return a CountingMeasurer for m
+func Counting[T, M any](m MeasurerOf[T, M]) *CountingMeasurer[T, M] {
+	return &CountingMeasurer[T, M]{measurer: m}
+}
+
+func (c *CountingMeasurer[T, M]) Identity() M { return c.measurer.Identity() }
+
+func (c *CountingMeasurer[T, M]) Measure(i T) M {
+	atomic.AddInt64(&c.measures, 1)
+	return c.measurer.Measure(i)
+}
+
+func (c *CountingMeasurer[T, M]) Sum(m1 M, m2 M) M {
+	atomic.AddInt64(&c.sums, 1)
+	return c.measurer.Sum(m1, m2)
+}
+
+// Counts return the number of Measure and Sum calls since the
+// measurer was made or last reset
+func (c *CountingMeasurer[T, M]) Counts() (measures, sums int64) {
+	return atomic.LoadInt64(&c.measures), atomic.LoadInt64(&c.sums)
+}
+
+// Reset set the counts to zero
+func (c *CountingMeasurer[T, M]) Reset() {
+	atomic.StoreInt64(&c.measures, 0)
+	atomic.StoreInt64(&c.sums, 0)
+}
@@ -0,0 +1,131 @@
+package typed
+
+// Cursor a position in a tree, with a focus item and the trees on
+// either side of it. Moving the cursor with Next and Prev and editing
+// at the focus are amortized O(1). Close returns the edited tree.
+//
+// When the cursor is past the last item it has no focus, so Insert
+// appends to the tree there.
+type Cursor[T, M any] struct {
+	measurer MeasurerOf[T, M]
+	left     splittable[T, M]
+	focus    elem[T, M]
+	hasFocus bool
+	right    splittable[T, M]
+	prefix   M
+}
+
+// NewCursor return a cursor focused on the first item of t that
+// satisfies p or a cursor past the last item if none does
+func NewCursor[T, M any](t Fingertree[T, M], p Predicate[M]) *Cursor[T, M] {
+	return newCursor(t, measured(p))
+}
+
+// NewCursorAt return a cursor focused on the item at index i of t,
+// or past the last item if i >= t.Len()
+func NewCursorAt[T, M any](t Fingertree[T, M], i int) *Cursor[T, M] {
+	return newCursor(t, counted[M](i))
+}
+
+func newCursor[T, M any](t Fingertree[T, M], s seeker[M]) *Cursor[T, M] {
+	tree := asSplittable(t).force()
+	m := tree.measurerOf()
+	c := &Cursor[T, M]{measurer: m, left: tree, right: newEmpty(m), prefix: tree.Measure()}
+	if !tree.IsEmpty() && s(pos[M]{tree.Len(), tree.Measure()}) {
+		split := tree.splitTree(s, start(m))
+		c.left, c.focus, c.hasFocus, c.right = split.left, split.mid, true, split.right
+		c.prefix = split.before.measure
+	}
+	return c
+}
+
+// Focus return the item at the cursor and whether there is one
+func (c *Cursor[T, M]) Focus() (T, bool) { return c.focus.item, c.hasFocus }
+
+// Prefix return the measurement of the items before the focus
+func (c *Cursor[T, M]) Prefix() M { return c.prefix }
+
+// Index return the index of the focus
+func (c *Cursor[T, M]) Index() int { return c.left.Len() }
+
+// Left return a tree of the items before the focus
+func (c *Cursor[T, M]) Left() Fingertree[T, M] { return c.left }
+
+// Right return a tree of the items after the focus
+func (c *Cursor[T, M]) Right() Fingertree[T, M] { return c.right }
+
+// Next move the cursor to the next item, returns whether there is
+// one. Moving past the last item leaves the cursor with no focus.
+func (c *Cursor[T, M]) Next() bool {
+	if !c.hasFocus {
+		return false
+	}
+	c.left = c.left.addLast(c.focus)
+	c.prefix = c.measurer.Sum(c.prefix, c.focus.measure())
+	c.pull()
+	return c.hasFocus
+}
+
+// Prev move the cursor to the previous item, returns false if the
+// cursor was already at the first item
+func (c *Cursor[T, M]) Prev() bool {
+	if c.left.IsEmpty() {
+		return false
+	}
+	if c.hasFocus {
+		c.right = c.right.addFirst(c.focus)
+	}
+	c.focus = c.left.lastElem()
+	c.hasFocus = true
+	c.left = c.left.removeLast()
+	c.prefix = c.left.Measure()
+	return true
+}
+
+// Insert add item at the cursor, it becomes the focus and the old
+// focus follows it
+func (c *Cursor[T, M]) Insert(item T) {
+	if c.hasFocus {
+		c.right = c.right.addFirst(c.focus)
+	}
+	c.focus = leaf(c.measurer, item)
+	c.hasFocus = true
+}
+
+// Delete remove the focus, the item after it becomes the new
+// focus. Returns whether there was a focus to delete.
+func (c *Cursor[T, M]) Delete() bool {
+	if !c.hasFocus {
+		return false
+	}
+	c.pull()
+	return true
+}
+
+// Replace change the focus to item, returns whether there was a
+// focus to replace
+func (c *Cursor[T, M]) Replace(item T) bool {
+	if !c.hasFocus {
+		return false
+	}
+	c.focus = leaf(c.measurer, item)
+	return true
+}
+
+// Close return a tree with the cursor's items
+func (c *Cursor[T, M]) Close() Fingertree[T, M] {
+	var items []elem[T, M]
+
+	if c.hasFocus {
+		items = []elem[T, M]{c.focus}
+	}
+	return app3(c.left, items, c.right)
+}
+
+// pull make the first item on the right the focus
+func (c *Cursor[T, M]) pull() {
+	c.focus, c.hasFocus = c.right.firstElem(), !c.right.IsEmpty()
+	if c.hasFocus {
+		c.right = c.right.removeFirst()
+	}
+}
@@ -0,0 +1,81 @@
+package typed
+
+// Filter return a tree of the items in t that match pred, in order.
+// The tree is built in one pass with t's measurer and the items keep
+// their measurements, so they are not measured again. Filtering a
+// Chunked tree makes a Chunked tree with the same chunk size, but
+// chunks only keep the measurement of all of their items, so the
+// items are measured again as they are put into the new chunks.
+func Filter[T, M any](t Fingertree[T, M], pred Code[T]) Fingertree[T, M] {
+	yes, _ := Partition(t, pred)
+	return yes
+}
+
+// Partition return a tree of the items in t that match pred and a
+// tree of the ones that do not, in one pass like Filter
+func Partition[T, M any](t Fingertree[T, M], pred Code[T]) (yes, no Fingertree[T, M]) {
+	groups := GroupBy(t, func(item T) bool { return pred(item) })
+	yes, no = groups[true], groups[false]
+	if yes == nil {
+		yes = emptyLike(t)
+	}
+	if no == nil {
+		no = emptyLike(t)
+	}
+	return yes, no
+}
+
+// GroupBy return trees of the items in t by their keys, in one pass
+// like Filter. Each tree keeps the order of its items in t and there
+// are only trees for keys that some item has.
+func GroupBy[T, M any, K comparable](t Fingertree[T, M], key func(T) K) map[K]Fingertree[T, M] {
+	result := map[K]Fingertree[T, M]{}
+	if c, ok := t.(*Chunked[T, M]); ok {
+		groups := map[K][]T{}
+		c.Each(func(item T) bool {
+			k := key(item)
+			groups[k] = append(groups[k], item)
+			return true
+		})
+		for k, items := range groups {
+			result[k] = c.fromItems(items)
+		}
+		return result
+	}
+	s := asSplittable(t)
+	builders := map[K]*builder[T, M]{}
+	eachLeaf(elem[T, M]{}, s, func(e elem[T, M]) {
+		k := key(e.item)
+		b := builders[k]
+		if b == nil {
+			b = &builder[T, M]{measurer: s.measurerOf()}
+			builders[k] = b
+		}
+		b.add(e)
+	})
+	for k, b := range builders {
+		result[k] = b.finish()
+	}
+	return result
+}
+
+// emptyLike return an empty tree like t
+func emptyLike[T, M any](t Fingertree[T, M]) Fingertree[T, M] {
+	if c, ok := t.(*Chunked[T, M]); ok {
+		return c.fromItems(nil)
+	}
+	return newEmpty(asSplittable(t).measurerOf())
+}
+
+// eachLeaf run f on the leaf elems of e, or tree t if it is not nil
+func eachLeaf[T, M any](e elem[T, M], t splittable[T, M], f func(elem[T, M])) {
+	fr, item, leaf := parts(e, t)
+	if leaf {
+		f(item)
+		return
+	}
+	for i := 0; i < fr.count(); i++ {
+		e, t := fr.part(i)
+		eachLeaf(e, t, f)
+	}
+}
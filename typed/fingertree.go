@@ -0,0 +1,1420 @@
+// Package typed implements finger trees with type parameters.
+//
+// It is the same structure as the parent fingertree package, but
+// items have type T and measurements have type M so Split, Find,
+// PeekFirst, Each, etc. are checked at compile time and items are
+// stored without being boxed in interfaces. The untyped
+// fingertree.Fingertree is a thin wrapper around
+// Fingertree[TreeItem, MeasureValue].
+//
+// Trees are persistent and safe to share between goroutines without
+// locks. A Cursor is a mutable view of a tree and is not.
+//
+// Internally, each level of a tree holds elems, which are either
+// items (at the top level) or nodes of elems from the level above
+// (in the middle trees).
+package typed
+
+import (
+	"iter"
+	"sync"
+)
+
+// Code a function that returns whether a tree item matches
+type Code[T any] func(T) bool
+
+// MeasuredCode a function that gets a tree item with the measurements
+// before and after it and returns whether to continue
+type MeasuredCode[T, M any] func(item T, before, after M) bool
+
+// Predicate a function that returns whether a measurement matches
+type Predicate[M any] func(M) bool
+
+// Traversable a traversable tree
+type Traversable[T, M any] interface {
+	// Measure return the measurement of the tree
+	Measure() M
+	// Each execute c on each item in the tree until it returns false
+	// or all the items have been processed. Returns whether all of
+	// the items were processed.
+	Each(c Code[T]) bool
+	// Each execute c on each item in the tree in reverse until it
+	// returns false or all the items have been processed. Returns
+	// whether all of the items were processed.
+	EachReverse(c Code[T]) bool
+	// All return an iter.Seq of the tree's items, for range loops and
+	// the slices and maps packages
+	All() iter.Seq[T]
+	// Backward return an iter.Seq of the tree's items in reverse
+	Backward() iter.Seq[T]
+	// EachWithMeasure is like Each but c also gets the measurement
+	// of the items before each item and of the items up to and
+	// including it, computed from the measurements the tree keeps
+	EachWithMeasure(c MeasuredCode[T, M]) bool
+	// EachReverseWithMeasure is like EachWithMeasure but it visits
+	// the items in reverse. The measurements are the same as
+	// EachWithMeasure's for each item.
+	EachReverseWithMeasure(c MeasuredCode[T, M]) bool
+}
+
+// Fingertree interface
+type Fingertree[T, M any] interface {
+	Traversable[T, M]
+	findable[T, M]
+	// IsEmpty return whether the tree is empty
+	IsEmpty() bool
+	// PeekFirst return the first item in the tree or the zero T if the tree is empty
+	PeekFirst() T
+	// PeekLast return the last item in the tree or the zero T if the tree is empty
+	PeekLast() T
+	// PeekFirstOK return the first item in the tree and whether there is one
+	PeekFirstOK() (T, bool)
+	// PeekLastOK return the last item in the tree and whether there is one
+	PeekLastOK() (T, bool)
+	// AddFirst return a tree containing i, followed by all of this tree's items
+	AddFirst(i T) Fingertree[T, M]
+	// AddLast return a tree containing all of this tree's items followed by i
+	AddLast(i T) Fingertree[T, M]
+	// RemoveFirst return a tree without the first item
+	RemoveFirst() Fingertree[T, M]
+	// RemoveLast return a tree without the last item
+	RemoveLast() Fingertree[T, M]
+	// Concat return a tree containing all of this tree's items,
+	// followed by all of tree's items
+	Concat(tree Fingertree[T, M]) Fingertree[T, M]
+	// AddAllFirst return a tree containing items, followed by all of
+	// this tree's items
+	AddAllFirst(items ...T) Fingertree[T, M]
+	// AddAllLast return a tree containing all of this tree's items
+	// followed by items
+	AddAllLast(items ...T) Fingertree[T, M]
+	// ConcatWith return a tree containing all of this tree's items,
+	// followed by items and then all of tree's items
+	ConcatWith(items []T, tree Fingertree[T, M]) Fingertree[T, M]
+	// Split return two trees, the first one containing all of the
+	// initial items that do not satisfy p and the second containing
+	// the items that follow them. If the first item that satisfies p
+	// is a SplittableItem, its initial piece that does not satisfy p
+	// ends the first tree and the rest of it starts the second.
+	Split(p Predicate[M]) []Fingertree[T, M]
+	// SplitAt3 return the initial items that do not satisfy p, the
+	// first item that does (the pivot), the items after it and the
+	// measurement of the items before the pivot. If no item
+	// satisfies p, ok is false, left is the whole tree and prefix is
+	// its measurement.
+	SplitAt3(p Predicate[M]) (left Fingertree[T, M], pivot T, right Fingertree[T, M], prefix M, ok bool)
+	// Search is like SplitAt3 but p sees the measurements on both
+	// sides of each item: the pivot is the first item where p is true
+	// for the measurement up to and including it and the measurement
+	// of the items after it, as in Hinze and Paterson's search
+	Search(p SearchPredicate[M]) (left Fingertree[T, M], pivot T, right Fingertree[T, M], prefix M, ok bool)
+	// TakeUntil return a tree containing the initial items that do
+	// not satisfy p, it is the first tree from Split
+	TakeUntil(p Predicate[M]) Fingertree[T, M]
+	// DropUntil return a tree with the initial items removed that do
+	// not satisfy p, it is the second tree from Split
+	DropUntil(p Predicate[M]) Fingertree[T, M]
+	// Find returns a pair with the last item that does not satisfy p
+	// and the first item that satisfies p. Missing items are the zero
+	// T. This much lighter weight than Split()
+	Find(p Predicate[M]) []T
+	// Locate is like Find but it also returns whether the items
+	// exist and the measurements around the first item that
+	// satisfies p
+	Locate(p Predicate[M]) FindResult[T, M]
+	// Len return the number of items in the tree
+	Len() int
+	// At return the item at index i or the zero T if i is out of range
+	At(i int) T
+	// SplitAt return two trees, the first one containing the first i
+	// items and the second containing the items that follow them
+	SplitAt(i int) []Fingertree[T, M]
+	// Take return a tree containing the first n items
+	Take(n int) Fingertree[T, M]
+	// Drop return a tree without the first n items
+	Drop(n int) Fingertree[T, M]
+	// EachBetween execute c on the items from the first one that
+	// satisfies from up to, but not including, the first one that
+	// satisfies to, until c returns false. Both predicates see
+	// measurements from the start of the tree, so these are the items
+	// of TakeUntil(to).DropUntil(from), but EachBetween does not build
+	// those trees or cut SplittableItems. Returns whether all of the
+	// items were processed.
+	EachBetween(from, to Predicate[M], c Code[T]) bool
+	// RangeMeasure return the measurement of the items EachBetween
+	// visits in O(log n), from the measurements the tree keeps
+	RangeMeasure(from, to Predicate[M]) M
+	// Compact evaluate all of the tree's delayed subtrees and drop
+	// the older trees they were computed from so they can be
+	// collected. Returns the tree, which has the same items.
+	Compact() Fingertree[T, M]
+}
+
+// Monoid combines measurements
+type Monoid[M any] interface {
+	// Identity return a "zero" measure value
+	Identity() M
+	// Sum return the sum of two measurements
+	Sum(measurement1 M, measurement2 M) M
+}
+
+// MeasurerOf measures items in a fingertree. Implement it with
+// methods on your own (value) type or use NewMeasurer to adapt
+// functions.
+type MeasurerOf[T, M any] interface {
+	Monoid[M]
+	// Measure return the measurement for i
+	Measure(i T) M
+}
+
+// measurer adapts functions to MeasurerOf
+type measurer[T, M any] struct {
+	identity func() M
+	measure  func(T) M
+	sum      func(M, M) M
+}
+
+// NewMeasurer create a measurer from functions
+func NewMeasurer[T, M any](identity func() M, measure func(T) M, sum func(M, M) M) MeasurerOf[T, M] {
+	return &measurer[T, M]{
+		identity,
+		measure,
+		sum,
+	}
+}
+
+func (m *measurer[T, M]) Identity() M      { return m.identity() }
+func (m *measurer[T, M]) Measure(i T) M    { return m.measure(i) }
+func (m *measurer[T, M]) Sum(m1 M, m2 M) M { return m.sum(m1, m2) }
+
+// strictMeasurer marks a measurer whose trees are evaluated strictly
+type strictMeasurer[T, M any] struct {
+	MeasurerOf[T, M]
+}
+
+func (m strictMeasurer[T, M]) strict() {}
+
+// Strict return a measurer like m for trees that never delay work.
+// Lazy trees defer rebuilding the middles of RemoveFirst, RemoveLast,
+// Split and Concat results until they are needed, which gives them
+// their amortized bounds but keeps the trees they were computed from
+// reachable until then. Trees made with a strict measurer do that
+// work right away, trading the amortized bounds for predictable
+// memory. Trees derived from them are also strict.
+func Strict[T, M any](m MeasurerOf[T, M]) MeasurerOf[T, M] {
+	if isStrict(m) {
+		return m
+	}
+	return strictMeasurer[T, M]{m}
+}
+
+func isStrict[T, M any](m MeasurerOf[T, M]) bool {
+	_, ok := m.(interface{ strict() })
+	return ok
+}
+
+// Empty makes an empty tree
+func Empty[T, M any](m MeasurerOf[T, M]) Fingertree[T, M] {
+	return newEmpty(m)
+}
+
+// With makes a tree for some items
+func With[T, M any](m MeasurerOf[T, M], xs ...T) Fingertree[T, M] {
+	return FromSlice(m, xs)
+}
+
+// FromSlice makes a balanced tree for items in O(n), measuring each
+// item once
+func FromSlice[T, M any](m MeasurerOf[T, M], items []T) Fingertree[T, M] {
+	return fromArray(m, leaves(m, items))
+}
+
+// Items returns an array of the items in t
+func Items[T, M any](t Fingertree[T, M]) []T {
+	var items []T
+
+	t.Each(func(item T) bool {
+		items = append(items, item)
+		return true
+	})
+	return items
+}
+
+// SplittableItem an item that can cut itself in two where a predicate
+// becomes true, like a piece of text in a rope or a run in a run-length
+// encoded sequence. Split, TakeUntil and DropUntil use it to split
+// trees inside of items.
+type SplittableItem[T, M any] interface {
+	// SplitItem return the initial piece of the item that does not
+	// satisfy p when it follows items measuring prefix, and the rest
+	// of the item. ok is false if the initial piece would be empty.
+	// The pieces' measurements should sum to the item's.
+	SplitItem(prefix M, p Predicate[M]) (left, right T, ok bool)
+}
+
+type findable[T, M any] interface {
+	first() T
+	last() T
+	find(s seeker[M], i pos[M], l T, hasL bool) FindResult[T, M]
+}
+
+// FindResult the items around the point where a predicate becomes
+// true. If no item satisfies it, HasAt is false and Prefix and
+// Measure are the measurement of the whole tree.
+type FindResult[T, M any] struct {
+	// Before the last item that does not satisfy the predicate
+	Before T
+	// At the first item that satisfies the predicate
+	At T
+	// HasBefore whether there is an item before At
+	HasBefore bool
+	// HasAt whether an item satisfies the predicate
+	HasAt bool
+	// Index the index of At
+	Index int
+	// Prefix the measurement of the items before At
+	Prefix M
+	// Measure the measurement of the items up to and including At
+	Measure M
+}
+
+type splittable[T, M any] interface {
+	Fingertree[T, M]
+	force() splittable[T, M]
+	compact() splittable[T, M]
+	measurerOf() MeasurerOf[T, M]
+	split(s seeker[M]) []Fingertree[T, M]
+	splitTree(s seeker[M], initial pos[M]) treeSplit[T, M]
+	searchTree(p SearchPredicate[M], initial pos[M], after M) treeSplit[T, M]
+	// eachMeasured run c on the items with before as the measurement
+	// of the items preceding the tree, returns the measurement after
+	// the last item c processed and whether it processed them all
+	eachMeasured(c MeasuredCode[T, M], before M) (M, bool)
+	eachReverseMeasured(c MeasuredCode[T, M], before M) bool
+	firstElem() elem[T, M]
+	lastElem() elem[T, M]
+	addFirst(e elem[T, M]) splittable[T, M]
+	addLast(e elem[T, M]) splittable[T, M]
+	removeFirst() splittable[T, M]
+	removeLast() splittable[T, M]
+	// measureTail return the measurement of all but the first elem
+	measureTail() M
+	// measureInit return the measurement of all but the last elem
+	measureInit() M
+}
+
+// pos a position in a tree: the number of items before it and their measurement
+type pos[M any] struct {
+	count   int
+	measure M
+}
+
+// seeker a predicate on positions, splitting and finding use these
+// so they work with both Predicates and indexes
+type seeker[M any] func(pos[M]) bool
+
+// elem is an item at the top level of a tree or a node in a middle
+// tree. Items are measured once, when they are added, and keep their
+// measurement so rebuilding digits and nodes, splitting and finding
+// do not call the measurer's Measure again.
+type elem[T, M any] struct {
+	item        T
+	measurement M
+	node        *node[T, M]
+}
+
+// the result of a low-level tree treeSplit
+type treeSplit[T, M any] struct {
+	// elements that do not satisfy the predicate
+	left splittable[T, M]
+	// the first element that satisfies the predicate
+	mid elem[T, M]
+	// the rest of the elements
+	right splittable[T, M]
+	// the position of mid
+	before pos[M]
+}
+
+// the result of a low-level digit split
+type digitSplit[T, M any] struct {
+	// elements that do not satisfy the predicate
+	left []elem[T, M]
+	// the first element that satisfies the predicate
+	mid elem[T, M]
+	// the rest of the elements
+	right []elem[T, M]
+	// the position of mid
+	before pos[M]
+}
+
+// digit is an internal part of a Fingertree, it holds 1-4 elems
+// inline so making one is a single allocation
+type digit[T, M any] struct {
+	array       [4]elem[T, M]
+	n           int8
+	size        int
+	measurement M
+}
+
+// node is an internal part of a Fingertree, it holds 2-3 elems inline
+type node[T, M any] struct {
+	array       [3]elem[T, M]
+	n           int8
+	size        int
+	measurement M
+}
+
+// empty an empty Fingertree
+type empty[T, M any] struct {
+	measurer MeasurerOf[T, M]
+}
+
+// single a Fingertree with one element
+type single[T, M any] struct {
+	measurer    MeasurerOf[T, M]
+	item        elem[T, M]
+	measurement M
+}
+
+// deep a Fingertree with more than one element
+type deep[T, M any] struct {
+	measurer    MeasurerOf[T, M]
+	left        *digit[T, M]
+	middle      splittable[T, M]
+	right       *digit[T, M]
+	size        int
+	measured    sync.Once
+	measurement M
+}
+
+// delayedFingertree a computed Fingertree, its size is known in
+// advance and its measurement is computed from the parts it will be
+// built from, so measuring it does not run the thunk. Trees are
+// persistent and may be shared between goroutines so each thunk runs
+// once and publishes its result through its sync.Once.
+type delayedFingertree[T, M any] struct {
+	measurer    MeasurerOf[T, M]
+	forced      sync.Once
+	thunk       func() splittable[T, M]
+	tree        splittable[T, M]
+	size        int
+	measured    sync.Once
+	measure     func() M
+	measurement M
+}
+
+func measured[M any](p Predicate[M]) seeker[M] {
+	return func(i pos[M]) bool { return p(i.measure) }
+}
+
+func counted[M any](n int) seeker[M] {
+	return func(i pos[M]) bool { return i.count > n }
+}
+
+func start[T, M any](m MeasurerOf[T, M]) pos[M] {
+	return pos[M]{0, m.Identity()}
+}
+
+func (i pos[M]) add(m Monoid[M], count int, measurement M) pos[M] {
+	return pos[M]{i.count + count, m.Sum(i.measure, measurement)}
+}
+
+func leaf[T, M any](m MeasurerOf[T, M], x T) elem[T, M] {
+	return elem[T, M]{item: x, measurement: m.Measure(x)}
+}
+
+func leaves[T, M any](m MeasurerOf[T, M], xs []T) []elem[T, M] {
+	items := make([]elem[T, M], len(xs))
+	for i, x := range xs {
+		items[i] = leaf(m, x)
+	}
+	return items
+}
+
+func measureItems[T, M any](m MeasurerOf[T, M], items []elem[T, M]) (int, M) {
+	if len(items) == 0 {
+		return 0, m.Identity()
+	}
+	size := items[0].size()
+	measurement := items[0].measure()
+	for _, item := range items[1:] {
+		size += item.size()
+		measurement = m.Sum(measurement, item.measure())
+	}
+	return size, measurement
+}
+
+// newDigit make a digit holding copies of items
+func newDigit[T, M any](m MeasurerOf[T, M], items []elem[T, M]) *digit[T, M] {
+	d := &digit[T, M]{n: int8(len(items))}
+	copy(d.array[:], items)
+	d.size, d.measurement = measureItems(m, items)
+	return d
+}
+
+// newNode make a node holding copies of items
+func newNode[T, M any](m MeasurerOf[T, M], items []elem[T, M]) elem[T, M] {
+	n := &node[T, M]{n: int8(len(items))}
+	copy(n.array[:], items)
+	n.size, n.measurement = measureItems(m, items)
+	return elem[T, M]{node: n}
+}
+
+func newEmpty[T, M any](m MeasurerOf[T, M]) *empty[T, M] {
+	return &empty[T, M]{m}
+}
+
+func newSingle[T, M any](m MeasurerOf[T, M], item elem[T, M]) *single[T, M] {
+	return &single[T, M]{m, item, item.measure()}
+}
+
+func newDeep[T, M any](m MeasurerOf[T, M], left *digit[T, M], middle splittable[T, M], right *digit[T, M]) *deep[T, M] {
+	if left.count() == 0 {
+		panic("Creating deep with empty left!")
+	}
+	if right.count() == 0 {
+		panic("Creating deep with empty right!")
+	}
+	return &deep[T, M]{measurer: m, left: left, middle: middle, right: right,
+		size: left.size + middle.Len() + right.size}
+}
+
+func newDelayedFingerTree[T, M any](m MeasurerOf[T, M], size int, measure func() M, f func() splittable[T, M]) splittable[T, M] {
+	if isStrict(m) {
+		return f()
+	}
+	return &delayedFingertree[T, M]{measurer: m, thunk: f, size: size, measure: measure}
+}
+
+func (e elem[T, M]) measure() M {
+	if e.node != nil {
+		return e.node.measurement
+	}
+	return e.measurement
+}
+
+func (e elem[T, M]) size() int {
+	if e.node != nil {
+		return e.node.size
+	}
+	return 1
+}
+
+func (e elem[T, M]) each(c Code[T]) bool {
+	if e.node != nil {
+		return traverse(e.node.elems(), c)
+	}
+	return c(e.item)
+}
+
+func (e elem[T, M]) eachReverse(c Code[T]) bool {
+	if e.node != nil {
+		return traverseReverse(e.node.elems(), c)
+	}
+	return c(e.item)
+}
+
+func (e elem[T, M]) eachMeasured(m Monoid[M], c MeasuredCode[T, M], before M) (M, bool) {
+	if e.node != nil {
+		return traverseMeasured(m, e.node.elems(), c, before)
+	}
+	after := m.Sum(before, e.measurement)
+	return after, c(e.item, before, after)
+}
+
+func (e elem[T, M]) eachReverseMeasured(m Monoid[M], c MeasuredCode[T, M], before M) bool {
+	if e.node != nil {
+		return traverseReverseMeasured(m, e.node.elems(), c, before)
+	}
+	return c(e.item, before, m.Sum(before, e.measurement))
+}
+
+func (e elem[T, M]) first() T {
+	for e.node != nil {
+		e = e.node.array[0]
+	}
+	return e.item
+}
+
+func (e elem[T, M]) last() T {
+	for e.node != nil {
+		e = e.node.array[e.node.n-1]
+	}
+	return e.item
+}
+
+// seq adapt an Each method to an iter.Seq
+func seq[T any](each func(Code[T]) bool) iter.Seq[T] {
+	return func(yield func(T) bool) { each(yield) }
+}
+
+func traverse[T, M any](items []elem[T, M], c Code[T]) bool {
+	for _, item := range items {
+		if !item.each(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func traverseMeasured[T, M any](m Monoid[M], items []elem[T, M], c MeasuredCode[T, M], before M) (M, bool) {
+	for _, item := range items {
+		var ok bool
+		if before, ok = item.eachMeasured(m, c, before); !ok {
+			return before, false
+		}
+	}
+	return before, true
+}
+
+// traverseReverseMeasured sum the measurements before each of the 1-4
+// items first, so the items can go in reverse
+func traverseReverseMeasured[T, M any](m Monoid[M], items []elem[T, M], c MeasuredCode[T, M], before M) bool {
+	var prefixes [4]M
+	prefixes[0] = before
+	for i := 1; i < len(items); i++ {
+		prefixes[i] = m.Sum(prefixes[i-1], items[i-1].measure())
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		if !items[i].eachReverseMeasured(m, c, prefixes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func traverseReverse[T, M any](items []elem[T, M], c Code[T]) bool {
+	for i := len(items) - 1; i >= 0; i-- {
+		if !items[i].eachReverse(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// find the leaf items on either side of the point where s becomes true
+func findItems[T, M any](m MeasurerOf[T, M], items []elem[T, M], s seeker[M], i pos[M], l T, hasL bool) FindResult[T, M] {
+	for n, item := range items {
+		next := i.add(m, item.size(), item.measure())
+		if s(next) {
+			if n > 0 {
+				l, hasL = items[n-1].last(), true
+			}
+			if item.node != nil {
+				return findItems(m, item.node.elems(), s, i, l, hasL)
+			}
+			return FindResult[T, M]{l, item.item, hasL, true, i.count, i.measure, next.measure}
+		}
+		i = next
+	}
+	return notFound[T](items[len(items)-1].last(), true, i)
+}
+
+func notFound[T, M any](l T, hasL bool, i pos[M]) FindResult[T, M] {
+	return FindResult[T, M]{Before: l, HasBefore: hasL, Index: i.count, Prefix: i.measure, Measure: i.measure}
+}
+
+// asSplittable return t as a splittable, trees with other
+// representations, like Chunked, are copied into a new tree
+func asSplittable[T, M any](t Fingertree[T, M]) splittable[T, M] {
+	if s, ok := t.(splittable[T, M]); ok {
+		return s
+	}
+	return t.(interface{ plain() splittable[T, M] }).plain()
+}
+
+// locate find the items around the point where s becomes true in t
+func locate[T, M any](t splittable[T, M], s seeker[M]) FindResult[T, M] {
+	var none T
+
+	return t.find(s, start(t.measurerOf()), none, false)
+}
+
+// split3 split t around the first item that satisfies s
+func split3[T, M any](t splittable[T, M], s seeker[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	var none T
+
+	m := t.measurerOf()
+	if t.IsEmpty() || !s(pos[M]{t.Len(), t.Measure()}) {
+		return t, none, newEmpty(m), t.Measure(), false
+	}
+	split := t.splitTree(s, start(m))
+	return split.left, split.mid.item, split.right, split.before.measure, true
+}
+
+// splitMeasured split t where p becomes true, cutting the first item
+// that satisfies p if it is a SplittableItem
+func splitMeasured[T, M any](t splittable[T, M], p Predicate[M]) []Fingertree[T, M] {
+	s := measured(p)
+	m := t.measurerOf()
+	if t.IsEmpty() || !s(pos[M]{t.Len(), t.Measure()}) {
+		return []Fingertree[T, M]{t, newEmpty(m)}
+	}
+	split := t.splitTree(s, start(m))
+	if item, ok := any(split.mid.item).(SplittableItem[T, M]); ok {
+		if left, right, ok := item.SplitItem(split.before.measure, p); ok {
+			return []Fingertree[T, M]{split.left.AddLast(left), split.right.AddFirst(right)}
+		}
+	}
+	return []Fingertree[T, M]{split.left, split.right.addFirst(split.mid)}
+}
+
+// at return the item at index i in t
+func at[T, M any](t splittable[T, M], i int) (item T) {
+	if 0 <= i && i < t.Len() {
+		item = locate(t, counted[M](i)).At
+	}
+	return
+}
+
+// elems return the digit's elems, the slice's capacity is its length
+// so appending to it cannot change the digit
+func (d *digit[T, M]) elems() []elem[T, M] { return d.array[:d.n:d.n] }
+
+// elems return the node's elems, the slice's capacity is its length
+// so appending to it cannot change the node
+func (n *node[T, M]) elems() []elem[T, M] { return n.array[:n.n:n.n] }
+
+func (d *digit[T, M]) each(c Code[T]) bool        { return traverse(d.elems(), c) }
+func (d *digit[T, M]) eachReverse(c Code[T]) bool { return traverseReverse(d.elems(), c) }
+func (d *digit[T, M]) count() int                 { return int(d.n) }
+func (d *digit[T, M]) first() T                   { return d.elems()[0].first() }
+func (d *digit[T, M]) last() T                    { return d.array[d.n-1].last() }
+
+// addFirst return a digit with item followed by d's elems, d must
+// have fewer than 4
+func (d *digit[T, M]) addFirst(m MeasurerOf[T, M], item elem[T, M]) *digit[T, M] {
+	result := &digit[T, M]{n: d.n + 1, size: item.size() + d.size, measurement: m.Sum(item.measure(), d.measurement)}
+	result.array[0] = item
+	copy(result.array[1:], d.elems())
+	return result
+}
+
+// addLast return a digit with d's elems followed by item, d must
+// have fewer than 4
+func (d *digit[T, M]) addLast(m MeasurerOf[T, M], item elem[T, M]) *digit[T, M] {
+	result := &digit[T, M]{array: d.array, n: d.n + 1, size: d.size + item.size(), measurement: m.Sum(d.measurement, item.measure())}
+	result.array[d.n] = item
+	return result
+}
+func (d *digit[T, M]) removeFirst(m MeasurerOf[T, M]) *digit[T, M] {
+	return d.slice(m, 1, d.count())
+}
+func (d *digit[T, M]) removeLast(m MeasurerOf[T, M]) *digit[T, M] {
+	return d.slice(m, 0, d.count()-1)
+}
+func (d *digit[T, M]) slice(m MeasurerOf[T, M], start, end int) *digit[T, M] {
+	return newDigit(m, d.elems()[start:end:end])
+}
+func (d *digit[T, M]) split(m MeasurerOf[T, M], s seeker[M], i pos[M]) digitSplit[T, M] {
+	return splitItems(m, d.elems(), s, i)
+}
+
+// splitItems split the elems of a digit or node
+func splitItems[T, M any](m MeasurerOf[T, M], items []elem[T, M], s seeker[M], i pos[M]) digitSplit[T, M] {
+	var item elem[T, M]
+	n := 0
+
+	if len(items) == 1 {
+		return digitSplit[T, M]{nil, items[0], nil, i}
+	}
+	before := i
+	for n, item = range items {
+		before = i
+		i = i.add(m, item.size(), item.measure())
+		if s(i) {
+			break
+		}
+	}
+	return digitSplit[T, M]{items[0:n:n], item, items[n+1:], before}
+}
+func (d *digit[T, M]) find(m MeasurerOf[T, M], s seeker[M], i pos[M], l T, hasL bool) FindResult[T, M] {
+	return findItems(m, d.elems(), s, i, l, hasL)
+}
+
+func (n *node[T, M]) toDigit() *digit[T, M] {
+	d := &digit[T, M]{n: n.n, size: n.size, measurement: n.measurement}
+	copy(d.array[:], n.elems())
+	return d
+}
+
+func (e *empty[T, M]) Measure() M                                    { return e.measurer.Identity() }
+func (e *empty[T, M]) IsEmpty() bool                                 { return true }
+func (e *empty[T, M]) Len() int                                      { return 0 }
+func (e *empty[T, M]) PeekFirst() (item T)                           { return }
+func (e *empty[T, M]) PeekLast() (item T)                            { return }
+func (e *empty[T, M]) PeekFirstOK() (item T, ok bool)                { return }
+func (e *empty[T, M]) PeekLastOK() (item T, ok bool)                 { return }
+func (e *empty[T, M]) At(i int) (item T)                             { return }
+func (e *empty[T, M]) first() (item T)                               { return }
+func (e *empty[T, M]) last() (item T)                                { return }
+func (e *empty[T, M]) firstElem() (item elem[T, M])                  { return }
+func (e *empty[T, M]) lastElem() (item elem[T, M])                   { return }
+func (e *empty[T, M]) AddFirst(i T) Fingertree[T, M]                 { return e.addFirst(leaf[T, M](e.measurer, i)) }
+func (e *empty[T, M]) AddLast(i T) Fingertree[T, M]                  { return e.addLast(leaf[T, M](e.measurer, i)) }
+func (e *empty[T, M]) addFirst(i elem[T, M]) splittable[T, M]        { return newSingle(e.measurer, i) }
+func (e *empty[T, M]) addLast(i elem[T, M]) splittable[T, M]         { return newSingle(e.measurer, i) }
+func (e *empty[T, M]) RemoveFirst() Fingertree[T, M]                 { return e }
+func (e *empty[T, M]) RemoveLast() Fingertree[T, M]                  { return e }
+func (e *empty[T, M]) removeFirst() splittable[T, M]                 { return e }
+func (e *empty[T, M]) removeLast() splittable[T, M]                  { return e }
+func (e *empty[T, M]) measureTail() M                                { return e.measurer.Identity() }
+func (e *empty[T, M]) measureInit() M                                { return e.measurer.Identity() }
+func (e *empty[T, M]) Concat(tree Fingertree[T, M]) Fingertree[T, M] { return tree }
+func (e *empty[T, M]) AddAllFirst(items ...T) Fingertree[T, M] {
+	return app3[T, M](e, leaves[T, M](e.measurer, items), e)
+}
+func (e *empty[T, M]) AddAllLast(items ...T) Fingertree[T, M] {
+	return app3[T, M](e, leaves[T, M](e.measurer, items), e)
+}
+func (e *empty[T, M]) ConcatWith(items []T, tree Fingertree[T, M]) Fingertree[T, M] {
+	return app3[T, M](e, leaves[T, M](e.measurer, items), asSplittable(tree))
+}
+func (e *empty[T, M]) Split(p Predicate[M]) []Fingertree[T, M] { return e.split(measured(p)) }
+func (e *empty[T, M]) SplitAt(i int) []Fingertree[T, M]        { return e.split(counted[M](i)) }
+func (e *empty[T, M]) split(s seeker[M]) []Fingertree[T, M]    { return []Fingertree[T, M]{e, e} }
+func (e *empty[T, M]) SplitAt3(p Predicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	return split3[T, M](e, measured(p))
+}
+func (e *empty[T, M]) TakeUntil(p Predicate[M]) Fingertree[T, M] { return e }
+func (e *empty[T, M]) DropUntil(p Predicate[M]) Fingertree[T, M] { return e }
+func (e *empty[T, M]) Take(n int) Fingertree[T, M]               { return e }
+func (e *empty[T, M]) Drop(n int) Fingertree[T, M]               { return e }
+func (e *empty[T, M]) Each(c Code[T]) bool                       { return true }
+func (e *empty[T, M]) EachReverse(c Code[T]) bool                { return true }
+func (e *empty[T, M]) All() iter.Seq[T]                          { return seq(e.Each) }
+func (e *empty[T, M]) Backward() iter.Seq[T]                     { return seq(e.EachReverse) }
+func (e *empty[T, M]) measurerOf() MeasurerOf[T, M]              { return e.measurer }
+func (e *empty[T, M]) force() splittable[T, M]                   { return e }
+func (e *empty[T, M]) compact() splittable[T, M]                 { return e }
+func (e *empty[T, M]) Compact() Fingertree[T, M]                 { return e }
+func (e *empty[T, M]) splitTree(s seeker[M], i pos[M]) treeSplit[T, M] {
+	return treeSplit[T, M]{left: e, right: e, before: i}
+}
+func (e *empty[T, M]) Find(p Predicate[M]) []T                { return make([]T, 2) }
+func (e *empty[T, M]) Locate(p Predicate[M]) FindResult[T, M] { return locate[T, M](e, measured(p)) }
+func (e *empty[T, M]) find(s seeker[M], i pos[M], l T, hasL bool) FindResult[T, M] {
+	return notFound[T](l, hasL, i)
+}
+func (e *empty[T, M]) EachWithMeasure(c MeasuredCode[T, M]) bool {
+	return true
+}
+func (e *empty[T, M]) EachReverseWithMeasure(c MeasuredCode[T, M]) bool {
+	return true
+}
+func (e *empty[T, M]) eachMeasured(c MeasuredCode[T, M], before M) (M, bool) {
+	return before, true
+}
+func (e *empty[T, M]) eachReverseMeasured(c MeasuredCode[T, M], before M) bool {
+	return true
+}
+func (e *empty[T, M]) EachBetween(from, to Predicate[M], c Code[T]) bool {
+	return true
+}
+func (e *empty[T, M]) RangeMeasure(from, to Predicate[M]) M {
+	return e.measurer.Identity()
+}
+
+func (s *single[T, M]) Measure() M                    { return s.measurement }
+func (s *single[T, M]) IsEmpty() bool                 { return false }
+func (s *single[T, M]) Len() int                      { return s.item.size() }
+func (s *single[T, M]) first() T                      { return s.item.first() }
+func (s *single[T, M]) last() T                       { return s.item.last() }
+func (s *single[T, M]) firstElem() elem[T, M]         { return s.item }
+func (s *single[T, M]) lastElem() elem[T, M]          { return s.item }
+func (s *single[T, M]) PeekFirst() T                  { return s.item.item }
+func (s *single[T, M]) PeekLast() T                   { return s.item.item }
+func (s *single[T, M]) PeekFirstOK() (T, bool)        { return s.item.item, true }
+func (s *single[T, M]) PeekLastOK() (T, bool)         { return s.item.item, true }
+func (s *single[T, M]) At(i int) T                    { return at[T, M](s, i) }
+func (s *single[T, M]) AddFirst(i T) Fingertree[T, M] { return s.addFirst(leaf[T, M](s.measurer, i)) }
+func (s *single[T, M]) AddLast(i T) Fingertree[T, M]  { return s.addLast(leaf[T, M](s.measurer, i)) }
+func (s *single[T, M]) addFirst(item elem[T, M]) splittable[T, M] {
+	return newDeep[T, M](s.measurer,
+		newDigit(s.measurer, []elem[T, M]{item}),
+		newEmpty(s.measurer),
+		newDigit(s.measurer, []elem[T, M]{s.item}))
+}
+func (s *single[T, M]) addLast(item elem[T, M]) splittable[T, M] {
+	return newDeep[T, M](s.measurer,
+		newDigit(s.measurer, []elem[T, M]{s.item}),
+		newEmpty(s.measurer),
+		newDigit(s.measurer, []elem[T, M]{item}))
+}
+func (s *single[T, M]) RemoveFirst() Fingertree[T, M] { return s.removeFirst() }
+func (s *single[T, M]) RemoveLast() Fingertree[T, M]  { return s.removeLast() }
+func (s *single[T, M]) removeFirst() splittable[T, M] { return newEmpty(s.measurer) }
+func (s *single[T, M]) removeLast() splittable[T, M]  { return newEmpty(s.measurer) }
+func (s *single[T, M]) measureTail() M                { return s.measurer.Identity() }
+func (s *single[T, M]) measureInit() M                { return s.measurer.Identity() }
+func (s *single[T, M]) Concat(other Fingertree[T, M]) Fingertree[T, M] {
+	return asSplittable(other).addFirst(s.item)
+}
+func (s *single[T, M]) AddAllFirst(items ...T) Fingertree[T, M] {
+	return app3[T, M](newEmpty(s.measurer), leaves[T, M](s.measurer, items), s)
+}
+func (s *single[T, M]) AddAllLast(items ...T) Fingertree[T, M] {
+	return app3[T, M](s, leaves[T, M](s.measurer, items), newEmpty(s.measurer))
+}
+func (s *single[T, M]) ConcatWith(items []T, tree Fingertree[T, M]) Fingertree[T, M] {
+	return app3[T, M](s, leaves[T, M](s.measurer, items), asSplittable(tree))
+}
+func (s *single[T, M]) Split(p Predicate[M]) []Fingertree[T, M] { return splitMeasured[T, M](s, p) }
+func (s *single[T, M]) SplitAt(i int) []Fingertree[T, M]        { return s.split(counted[M](i)) }
+func (s *single[T, M]) split(sk seeker[M]) []Fingertree[T, M] {
+	if sk(pos[M]{s.Len(), s.measurement}) {
+		return []Fingertree[T, M]{newEmpty(s.measurer), s}
+	}
+	return []Fingertree[T, M]{s, newEmpty(s.measurer)}
+}
+func (s *single[T, M]) Find(p Predicate[M]) []T {
+	r := locate[T, M](s, measured(p))
+	return []T{r.Before, r.At}
+}
+func (s *single[T, M]) Locate(p Predicate[M]) FindResult[T, M] { return locate[T, M](s, measured(p)) }
+func (s *single[T, M]) SplitAt3(p Predicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	return split3[T, M](s, measured(p))
+}
+func (s *single[T, M]) TakeUntil(p Predicate[M]) Fingertree[T, M] { return s.Split(p)[0] }
+func (s *single[T, M]) DropUntil(p Predicate[M]) Fingertree[T, M] { return s.Split(p)[1] }
+func (s *single[T, M]) Take(n int) Fingertree[T, M]               { return s.split(counted[M](n))[0] }
+func (s *single[T, M]) Drop(n int) Fingertree[T, M]               { return s.split(counted[M](n))[1] }
+func (s *single[T, M]) Each(c Code[T]) bool                       { return s.item.each(c) }
+func (s *single[T, M]) EachReverse(c Code[T]) bool                { return s.item.eachReverse(c) }
+func (s *single[T, M]) All() iter.Seq[T]                          { return seq(s.Each) }
+func (s *single[T, M]) Backward() iter.Seq[T]                     { return seq(s.EachReverse) }
+func (s *single[T, M]) measurerOf() MeasurerOf[T, M]              { return s.measurer }
+func (s *single[T, M]) force() splittable[T, M]                   { return s }
+func (s *single[T, M]) compact() splittable[T, M]                 { return s }
+func (s *single[T, M]) Compact() Fingertree[T, M]                 { return s }
+func (s *single[T, M]) splitTree(sk seeker[M], initial pos[M]) treeSplit[T, M] {
+	return treeSplit[T, M]{newEmpty(s.measurer), s.item, newEmpty(s.measurer), initial}
+}
+func (s *single[T, M]) find(sk seeker[M], i pos[M], l T, hasL bool) FindResult[T, M] {
+	next := i.add(s.measurer, s.Len(), s.measurement)
+	if !sk(next) {
+		return notFound[T](s.item.last(), true, next)
+	}
+	if s.item.node != nil {
+		return findItems(s.measurer, s.item.node.elems(), sk, i, l, hasL)
+	}
+	return FindResult[T, M]{l, s.item.item, hasL, true, i.count, i.measure, next.measure}
+}
+func (s *single[T, M]) EachWithMeasure(c MeasuredCode[T, M]) bool {
+	_, ok := s.eachMeasured(c, s.measurer.Identity())
+	return ok
+}
+func (s *single[T, M]) EachReverseWithMeasure(c MeasuredCode[T, M]) bool {
+	return s.eachReverseMeasured(c, s.measurer.Identity())
+}
+func (s *single[T, M]) eachMeasured(c MeasuredCode[T, M], before M) (M, bool) {
+	return s.item.eachMeasured(s.measurer, c, before)
+}
+func (s *single[T, M]) eachReverseMeasured(c MeasuredCode[T, M], before M) bool {
+	return s.item.eachReverseMeasured(s.measurer, c, before)
+}
+func (s *single[T, M]) EachBetween(from, to Predicate[M], c Code[T]) bool {
+	return eachBetween[T, M](s, from, to, c)
+}
+func (s *single[T, M]) RangeMeasure(from, to Predicate[M]) M {
+	return rangeMeasure[T, M](s, from, to)
+}
+
+func (d *deep[T, M]) Measure() M {
+	d.measured.Do(d.measure)
+	return d.measurement
+}
+func (d *deep[T, M]) measure() {
+	m := d.measurer
+	d.measurement = m.Sum(m.Sum(d.left.measurement, d.middle.Measure()), d.right.measurement)
+}
+func (d *deep[T, M]) IsEmpty() bool             { return false }
+func (d *deep[T, M]) Len() int                  { return d.size }
+func (d *deep[T, M]) PeekFirst() T              { return d.left.first() }
+func (d *deep[T, M]) PeekLast() T               { return d.right.last() }
+func (d *deep[T, M]) PeekFirstOK() (T, bool)    { return d.left.first(), true }
+func (d *deep[T, M]) PeekLastOK() (T, bool)     { return d.right.last(), true }
+func (d *deep[T, M]) At(i int) T                { return at[T, M](d, i) }
+func (d *deep[T, M]) first() T                  { return d.left.first() }
+func (d *deep[T, M]) last() T                   { return d.right.last() }
+func (d *deep[T, M]) firstElem() elem[T, M]     { return d.left.array[0] }
+func (d *deep[T, M]) lastElem() elem[T, M]      { return d.right.array[d.right.n-1] }
+func (d *deep[T, M]) force() splittable[T, M]   { return d }
+func (d *deep[T, M]) Compact() Fingertree[T, M] { return d.compact() }
+func (d *deep[T, M]) compact() splittable[T, M] {
+	d.middle.compact()
+	return d
+}
+func (d *deep[T, M]) measurerOf() MeasurerOf[T, M] { return d.measurer }
+func (d *deep[T, M]) AddFirst(i T) Fingertree[T, M] {
+	return d.addFirst(leaf[T, M](d.measurer, i))
+}
+func (d *deep[T, M]) AddLast(i T) Fingertree[T, M] {
+	return d.addLast(leaf[T, M](d.measurer, i))
+}
+func (d *deep[T, M]) addFirst(item elem[T, M]) splittable[T, M] {
+	m := d.measurer
+	if d.left.count() == 4 {
+		return newDeep(m,
+			newDigit(m, []elem[T, M]{item, d.left.array[0]}),
+			d.middle.addFirst(newNode(m, d.left.array[1:])),
+			d.right)
+	}
+	return newDeep(m, d.left.addFirst(m, item), d.middle, d.right)
+}
+func (d *deep[T, M]) addLast(item elem[T, M]) splittable[T, M] {
+	m := d.measurer
+	if d.right.count() == 4 {
+		return newDeep(m,
+			d.left,
+			d.middle.addLast(newNode(m, d.right.array[0:3])),
+			newDigit(m, []elem[T, M]{d.right.array[3], item}))
+	}
+	return newDeep(m, d.left, d.middle, d.right.addLast(m, item))
+}
+func (d *deep[T, M]) RemoveFirst() Fingertree[T, M] { return d.removeFirst() }
+func (d *deep[T, M]) RemoveLast() Fingertree[T, M]  { return d.removeLast() }
+func (d *deep[T, M]) removeFirst() splittable[T, M] {
+	m := d.measurer
+	if d.left.count() > 1 {
+		return newDeep(m, d.left.removeFirst(m), d.middle, d.right)
+	}
+	if !d.middle.IsEmpty() {
+		first := d.middle.firstElem().node
+		newMid := newDelayedFingerTree(m, d.middle.Len()-first.size, d.middle.measureTail,
+			func() splittable[T, M] { return d.middle.removeFirst() })
+		return newDeep(m, first.toDigit(), newMid, d.right)
+	}
+	if d.right.count() == 1 {
+		return newSingle(m, d.right.elems()[0])
+	}
+	return newDeep(m, d.right.slice(m, 0, 1), d.middle, d.right.removeFirst(m))
+}
+func (d *deep[T, M]) removeLast() splittable[T, M] {
+	m := d.measurer
+	if d.right.count() > 1 {
+		return newDeep(m, d.left, d.middle, d.right.removeLast(m))
+	}
+	if !d.middle.IsEmpty() {
+		last := d.middle.lastElem().node
+		newMid := newDelayedFingerTree(m, d.middle.Len()-last.size, d.middle.measureInit,
+			func() splittable[T, M] { return d.middle.removeLast() })
+		return newDeep(m, d.left, newMid, last.toDigit())
+	}
+	l := d.left
+	if l.count() == 1 {
+		return newSingle(m, l.elems()[0])
+	}
+	return newDeep(m, l.removeLast(m), d.middle, l.slice(m, l.count()-1, l.count()))
+}
+func (d *deep[T, M]) measureTail() M {
+	m := d.measurer
+	rest := m.Sum(d.middle.Measure(), d.right.measurement)
+	if d.left.count() == 1 {
+		return rest
+	}
+	_, tail := measureItems(m, d.left.elems()[1:])
+	return m.Sum(tail, rest)
+}
+func (d *deep[T, M]) measureInit() M {
+	m := d.measurer
+	rest := m.Sum(d.left.measurement, d.middle.Measure())
+	if d.right.count() == 1 {
+		return rest
+	}
+	_, init := measureItems(m, d.right.elems()[:d.right.count()-1])
+	return m.Sum(rest, init)
+}
+func (d *deep[T, M]) Concat(other Fingertree[T, M]) Fingertree[T, M] {
+	switch o := asSplittable(other).force().(type) {
+	case *empty[T, M]:
+		return d
+	case *single[T, M]:
+		return d.addLast(o.item)
+	default:
+		return app3[T, M](d, nil, o)
+	}
+}
+func (d *deep[T, M]) AddAllFirst(items ...T) Fingertree[T, M] {
+	return app3[T, M](newEmpty(d.measurer), leaves[T, M](d.measurer, items), d)
+}
+func (d *deep[T, M]) AddAllLast(items ...T) Fingertree[T, M] {
+	return app3[T, M](d, leaves[T, M](d.measurer, items), newEmpty(d.measurer))
+}
+func (d *deep[T, M]) ConcatWith(items []T, tree Fingertree[T, M]) Fingertree[T, M] {
+	return app3[T, M](d, leaves[T, M](d.measurer, items), asSplittable(tree))
+}
+func (d *deep[T, M]) splitTree(s seeker[M], initial pos[M]) treeSplit[T, M] {
+	m := d.measurer
+	leftPos := initial.add(m, d.left.size, d.left.measurement)
+	if s(leftPos) {
+		dsplit := d.left.split(m, s, initial)
+		return treeSplit[T, M]{fromArray(m, dsplit.left),
+			dsplit.mid,
+			deepLeft(m, dsplit.right, d.middle, d.right),
+			dsplit.before}
+	}
+	midPos := leftPos.add(m, d.middle.Len(), d.middle.Measure())
+	if s(midPos) {
+		midSplit := d.middle.splitTree(s, leftPos)
+		split := splitItems(m, midSplit.mid.node.elems(), s,
+			leftPos.add(m, midSplit.left.Len(), midSplit.left.Measure()))
+		return treeSplit[T, M]{deepRight(m, d.left, midSplit.left, split.left),
+			split.mid,
+			deepLeft(m, split.right, midSplit.right, d.right),
+			split.before}
+	}
+	dsplit := d.right.split(m, s, midPos)
+	return treeSplit[T, M]{deepRight(m, d.left, d.middle, dsplit.left),
+		dsplit.mid,
+		fromArray(m, dsplit.right),
+		dsplit.before}
+}
+func (d *deep[T, M]) Split(p Predicate[M]) []Fingertree[T, M] { return splitMeasured[T, M](d, p) }
+func (d *deep[T, M]) SplitAt(i int) []Fingertree[T, M]        { return d.split(counted[M](i)) }
+func (d *deep[T, M]) split(s seeker[M]) []Fingertree[T, M] {
+	if s(pos[M]{d.size, d.Measure()}) {
+		split := d.splitTree(s, start(d.measurer))
+		return []Fingertree[T, M]{split.left, split.right.addFirst(split.mid)}
+	}
+	return []Fingertree[T, M]{d, newEmpty(d.measurer)}
+}
+func (d *deep[T, M]) Find(p Predicate[M]) []T {
+	r := locate[T, M](d, measured(p))
+	return []T{r.Before, r.At}
+}
+func (d *deep[T, M]) Locate(p Predicate[M]) FindResult[T, M] { return locate[T, M](d, measured(p)) }
+func (d *deep[T, M]) find(s seeker[M], i pos[M], l T, hasL bool) FindResult[T, M] {
+	m := d.measurer
+	leftPos := i.add(m, d.left.size, d.left.measurement)
+	if s(leftPos) {
+		return d.left.find(m, s, i, l, hasL)
+	}
+	midPos := leftPos.add(m, d.middle.Len(), d.middle.Measure())
+	l = d.left.last()
+	if s(midPos) {
+		return d.middle.find(s, leftPos, l, true)
+	}
+	if !d.middle.IsEmpty() {
+		l = d.middle.last()
+	}
+	return d.right.find(m, s, midPos, l, true)
+}
+func (d *deep[T, M]) SplitAt3(p Predicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	return split3[T, M](d, measured(p))
+}
+func (d *deep[T, M]) TakeUntil(p Predicate[M]) Fingertree[T, M] { return d.Split(p)[0] }
+func (d *deep[T, M]) DropUntil(p Predicate[M]) Fingertree[T, M] { return d.Split(p)[1] }
+func (d *deep[T, M]) Take(n int) Fingertree[T, M]               { return d.split(counted[M](n))[0] }
+func (d *deep[T, M]) Drop(n int) Fingertree[T, M]               { return d.split(counted[M](n))[1] }
+
+func (d *deep[T, M]) Each(c Code[T]) bool {
+	if !d.left.each(c) {
+		return false
+	}
+	if !d.middle.Each(c) {
+		return false
+	}
+	return d.right.each(c)
+}
+func (d *deep[T, M]) EachReverse(c Code[T]) bool {
+	if !d.right.eachReverse(c) {
+		return false
+	}
+	if !d.middle.EachReverse(c) {
+		return false
+	}
+	return d.left.eachReverse(c)
+}
+func (d *deep[T, M]) All() iter.Seq[T]      { return seq(d.Each) }
+func (d *deep[T, M]) Backward() iter.Seq[T] { return seq(d.EachReverse) }
+func (d *deep[T, M]) EachWithMeasure(c MeasuredCode[T, M]) bool {
+	_, ok := d.eachMeasured(c, d.measurer.Identity())
+	return ok
+}
+func (d *deep[T, M]) EachReverseWithMeasure(c MeasuredCode[T, M]) bool {
+	return d.eachReverseMeasured(c, d.measurer.Identity())
+}
+func (d *deep[T, M]) eachMeasured(c MeasuredCode[T, M], before M) (M, bool) {
+	var ok bool
+	if before, ok = traverseMeasured(d.measurer, d.left.elems(), c, before); !ok {
+		return before, false
+	}
+	if before, ok = d.middle.eachMeasured(c, before); !ok {
+		return before, false
+	}
+	return traverseMeasured(d.measurer, d.right.elems(), c, before)
+}
+func (d *deep[T, M]) eachReverseMeasured(c MeasuredCode[T, M], before M) bool {
+	m := d.measurer
+	afterLeft := m.Sum(before, d.left.measurement)
+	afterMiddle := m.Sum(afterLeft, d.middle.Measure())
+	if !traverseReverseMeasured(m, d.right.elems(), c, afterMiddle) {
+		return false
+	}
+	if !d.middle.eachReverseMeasured(c, afterLeft) {
+		return false
+	}
+	return traverseReverseMeasured(m, d.left.elems(), c, before)
+}
+func (d *deep[T, M]) EachBetween(from, to Predicate[M], c Code[T]) bool {
+	return eachBetween[T, M](d, from, to, c)
+}
+func (d *deep[T, M]) RangeMeasure(from, to Predicate[M]) M {
+	return rangeMeasure[T, M](d, from, to)
+}
+
+func (d *delayedFingertree[T, M]) Measure() M {
+	d.measured.Do(d.evaluateMeasure)
+	return d.measurement
+}
+func (d *delayedFingertree[T, M]) IsEmpty() bool                 { return d.size == 0 }
+func (d *delayedFingertree[T, M]) Len() int                      { return d.size }
+func (d *delayedFingertree[T, M]) PeekFirst() T                  { return d.force().PeekFirst() }
+func (d *delayedFingertree[T, M]) PeekLast() T                   { return d.force().PeekLast() }
+func (d *delayedFingertree[T, M]) PeekFirstOK() (T, bool)        { return d.force().PeekFirstOK() }
+func (d *delayedFingertree[T, M]) PeekLastOK() (T, bool)         { return d.force().PeekLastOK() }
+func (d *delayedFingertree[T, M]) At(i int) T                    { return d.force().At(i) }
+func (d *delayedFingertree[T, M]) first() T                      { return d.force().first() }
+func (d *delayedFingertree[T, M]) last() T                       { return d.force().last() }
+func (d *delayedFingertree[T, M]) firstElem() elem[T, M]         { return d.force().firstElem() }
+func (d *delayedFingertree[T, M]) lastElem() elem[T, M]          { return d.force().lastElem() }
+func (d *delayedFingertree[T, M]) AddFirst(i T) Fingertree[T, M] { return d.force().AddFirst(i) }
+func (d *delayedFingertree[T, M]) AddLast(i T) Fingertree[T, M]  { return d.force().AddLast(i) }
+func (d *delayedFingertree[T, M]) addFirst(i elem[T, M]) splittable[T, M] {
+	return d.force().addFirst(i)
+}
+func (d *delayedFingertree[T, M]) addLast(i elem[T, M]) splittable[T, M] {
+	return d.force().addLast(i)
+}
+func (d *delayedFingertree[T, M]) RemoveFirst() Fingertree[T, M] { return d.force().RemoveFirst() }
+func (d *delayedFingertree[T, M]) RemoveLast() Fingertree[T, M]  { return d.force().RemoveLast() }
+func (d *delayedFingertree[T, M]) removeFirst() splittable[T, M] { return d.force().removeFirst() }
+func (d *delayedFingertree[T, M]) removeLast() splittable[T, M]  { return d.force().removeLast() }
+func (d *delayedFingertree[T, M]) measureTail() M                { return d.force().measureTail() }
+func (d *delayedFingertree[T, M]) measureInit() M                { return d.force().measureInit() }
+func (d *delayedFingertree[T, M]) Concat(t Fingertree[T, M]) Fingertree[T, M] {
+	return d.force().Concat(t)
+}
+func (d *delayedFingertree[T, M]) AddAllFirst(items ...T) Fingertree[T, M] {
+	return d.force().AddAllFirst(items...)
+}
+func (d *delayedFingertree[T, M]) AddAllLast(items ...T) Fingertree[T, M] {
+	return d.force().AddAllLast(items...)
+}
+func (d *delayedFingertree[T, M]) ConcatWith(items []T, t Fingertree[T, M]) Fingertree[T, M] {
+	return d.force().ConcatWith(items, t)
+}
+func (d *delayedFingertree[T, M]) Split(p Predicate[M]) []Fingertree[T, M] {
+	return d.force().Split(p)
+}
+func (d *delayedFingertree[T, M]) SplitAt(i int) []Fingertree[T, M] { return d.force().SplitAt(i) }
+func (d *delayedFingertree[T, M]) split(s seeker[M]) []Fingertree[T, M] {
+	return d.force().split(s)
+}
+func (d *delayedFingertree[T, M]) SplitAt3(p Predicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	return d.force().SplitAt3(p)
+}
+func (d *delayedFingertree[T, M]) TakeUntil(p Predicate[M]) Fingertree[T, M] {
+	return d.force().TakeUntil(p)
+}
+func (d *delayedFingertree[T, M]) DropUntil(p Predicate[M]) Fingertree[T, M] {
+	return d.force().DropUntil(p)
+}
+func (d *delayedFingertree[T, M]) Take(n int) Fingertree[T, M] { return d.force().Take(n) }
+func (d *delayedFingertree[T, M]) Drop(n int) Fingertree[T, M] { return d.force().Drop(n) }
+func (d *delayedFingertree[T, M]) Each(c Code[T]) bool         { return d.force().Each(c) }
+func (d *delayedFingertree[T, M]) EachReverse(c Code[T]) bool  { return d.force().EachReverse(c) }
+func (d *delayedFingertree[T, M]) All() iter.Seq[T]            { return seq(d.Each) }
+func (d *delayedFingertree[T, M]) Backward() iter.Seq[T]       { return seq(d.EachReverse) }
+func (d *delayedFingertree[T, M]) EachWithMeasure(c MeasuredCode[T, M]) bool {
+	return d.force().EachWithMeasure(c)
+}
+func (d *delayedFingertree[T, M]) EachReverseWithMeasure(c MeasuredCode[T, M]) bool {
+	return d.force().EachReverseWithMeasure(c)
+}
+func (d *delayedFingertree[T, M]) eachMeasured(c MeasuredCode[T, M], before M) (M, bool) {
+	return d.force().eachMeasured(c, before)
+}
+func (d *delayedFingertree[T, M]) eachReverseMeasured(c MeasuredCode[T, M], before M) bool {
+	return d.force().eachReverseMeasured(c, before)
+}
+func (d *delayedFingertree[T, M]) EachBetween(from, to Predicate[M], c Code[T]) bool {
+	return eachBetween[T, M](d, from, to, c)
+}
+func (d *delayedFingertree[T, M]) RangeMeasure(from, to Predicate[M]) M {
+	return rangeMeasure[T, M](d, from, to)
+}
+
+func (d *delayedFingertree[T, M]) Find(p Predicate[M]) []T { return d.force().Find(p) }
+func (d *delayedFingertree[T, M]) Locate(p Predicate[M]) FindResult[T, M] {
+	return d.force().Locate(p)
+}
+func (d *delayedFingertree[T, M]) find(s seeker[M], i pos[M], l T, hasL bool) FindResult[T, M] {
+	return d.force().find(s, i, l, hasL)
+}
+
+func (d *delayedFingertree[T, M]) force() splittable[T, M] {
+	d.forced.Do(d.evaluate)
+	return d.tree
+}
+
+// evaluate run the thunk and drop it so the trees it captured can be
+// collected
+func (d *delayedFingertree[T, M]) evaluate() {
+	d.tree = d.thunk()
+	d.thunk = nil
+}
+
+func (d *delayedFingertree[T, M]) Compact() Fingertree[T, M] { return d.compact() }
+func (d *delayedFingertree[T, M]) compact() splittable[T, M] {
+	tree := d.force().compact()
+	d.measured.Do(d.measureForced)
+	return tree
+}
+
+// measureForced take the measurement from the evaluated tree so the
+// parts the measure function captured can be collected
+func (d *delayedFingertree[T, M]) measureForced() {
+	d.measurement = d.tree.Measure()
+	d.measure = nil
+}
+
+// evaluateMeasure compute the measurement from the parts the tree is
+// built from, this only forces a lazy middle when it has to measure
+// around the elem the thunk will remove from it
+func (d *delayedFingertree[T, M]) evaluateMeasure() {
+	d.measurement = d.measure()
+	d.measure = nil
+}
+
+func (d *delayedFingertree[T, M]) measurerOf() MeasurerOf[T, M] { return d.measurer }
+
+func (d *delayedFingertree[T, M]) splitTree(s seeker[M], i pos[M]) treeSplit[T, M] {
+	return d.force().splitTree(s, i)
+}
+
+func deepLeft[T, M any](m MeasurerOf[T, M], left []elem[T, M], mid splittable[T, M], right *digit[T, M]) splittable[T, M] {
+	if len(left) > 0 {
+		return newDeep(m, newDigit(m, left), mid, right)
+	}
+	if mid.IsEmpty() {
+		return fromArray(m, right.elems())
+	}
+	return newDelayedFingerTree(m, mid.Len()+right.size,
+		func() M { return m.Sum(mid.Measure(), right.measurement) },
+		func() splittable[T, M] {
+			return newDeep(m, mid.firstElem().node.toDigit(), mid.removeFirst(), right)
+		})
+}
+
+func deepRight[T, M any](m MeasurerOf[T, M], left *digit[T, M], mid splittable[T, M], right []elem[T, M]) splittable[T, M] {
+	if len(right) > 0 {
+		return newDeep(m, left, mid, newDigit(m, right))
+	}
+	if mid.IsEmpty() {
+		return fromArray(m, left.elems())
+	}
+	return newDelayedFingerTree(m, left.size+mid.Len(),
+		func() M { return m.Sum(left.measurement, mid.Measure()) },
+		func() splittable[T, M] {
+			return newDeep(m, left, mid.removeLast(), mid.lastElem().node.toDigit())
+		})
+}
+
+// concatenate two fingertrees with additional elements in between
+func app3[T, M any](t1 splittable[T, M], items []elem[T, M], t2 splittable[T, M]) splittable[T, M] {
+	t1 = t1.force()
+	t2 = t2.force()
+	if len(items) > 4 {
+		// build the items into a tree and join the three trees
+		if _, ok := t1.(*empty[T, M]); ok {
+			return app3(fromArray(t1.measurerOf(), items), nil, t2)
+		}
+		if _, ok := t2.(*empty[T, M]); ok {
+			return app3(t1, nil, fromArray(t2.measurerOf(), items))
+		}
+	}
+	if _, ok := t1.(*empty[T, M]); ok {
+		return prependItems(t2, items)
+	}
+	if _, ok := t2.(*empty[T, M]); ok {
+		return appendItems(t1, items)
+	}
+	if s, ok := t1.(*single[T, M]); ok {
+		if len(items) > 4 {
+			return app3(fromArray(s.measurer, append([]elem[T, M]{s.item}, items...)), nil, t2)
+		}
+		return prependItems(t2, items).addFirst(s.item)
+	}
+	if s, ok := t2.(*single[T, M]); ok {
+		if len(items) > 4 {
+			return app3(t1, nil, fromArray(s.measurer, append(items[:len(items):len(items)], s.item)))
+		}
+		return appendItems(t1, items).addLast(s.item)
+	}
+	d1 := t1.(*deep[T, M])
+	d2 := t2.(*deep[T, M])
+	m := d1.measurer
+	itemsSize, itemsMeasure := measureItems(m, items)
+	size := d1.middle.Len() + d1.right.size + itemsSize + d2.left.size + d2.middle.Len()
+	return newDeep(m,
+		d1.left,
+		newDelayedFingerTree(m, size, func() M {
+			inner := m.Sum(m.Sum(d1.right.measurement, itemsMeasure), d2.left.measurement)
+			return m.Sum(m.Sum(d1.middle.Measure(), inner), d2.middle.Measure())
+		}, func() splittable[T, M] {
+			newNodes := make([]elem[T, M], 0, len(d1.right.elems())+len(items)+len(d2.left.elems()))
+			return app3(d1.middle,
+				nodes(d1.measurer,
+					append(append(append(newNodes, d1.right.elems()...), items...), d2.left.elems()...),
+					nil),
+				d2.middle)
+		}),
+		d2.right)
+}
+
+func nodes[T, M any](m MeasurerOf[T, M], xs []elem[T, M], result []elem[T, M]) []elem[T, M] {
+	for len(xs) > 4 {
+		result = append(result, newNode(m, xs[0:3:3]))
+		xs = xs[3:]
+	}
+	switch len(xs) {
+	case 2, 3:
+		return append(result, newNode(m, xs))
+	default:
+		return append(result, newNode(m, xs[0:2:2]), newNode(m, xs[2:]))
+	}
+}
+
+func prependItems[T, M any](tree splittable[T, M], items []elem[T, M]) splittable[T, M] {
+	for i := len(items) - 1; i >= 0; i-- {
+		tree = tree.addFirst(items[i])
+	}
+	return tree
+}
+
+func appendItems[T, M any](tree splittable[T, M], items []elem[T, M]) splittable[T, M] {
+	for _, i := range items {
+		tree = tree.addLast(i)
+	}
+	return tree
+}
+
+// fromArray build a tree bottom-up: the ends become digits and the
+// rest are grouped into nodes for the middle tree
+func fromArray[T, M any](m MeasurerOf[T, M], xs []elem[T, M]) splittable[T, M] {
+	switch n := len(xs); {
+	case n == 0:
+		return newEmpty(m)
+	case n == 1:
+		return newSingle(m, xs[0])
+	case n <= 8:
+		return newDeep[T, M](m, newDigit(m, xs[:n/2:n/2]), newEmpty(m), newDigit(m, xs[n/2:]))
+	default:
+		return newDeep(m,
+			newDigit(m, xs[:3:3]),
+			fromArray(m, nodes(m, xs[3:n-3], make([]elem[T, M], 0, (n-6+2)/3))),
+			newDigit(m, xs[n-3:]))
+	}
+}
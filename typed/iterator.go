@@ -0,0 +1,357 @@
+package typed
+
+import "iter"
+
+// Iterator a position between two items of a tree, or at one of its
+// ends. Next returns the item after the position and moves past it,
+// Prev returns the item before the position and moves before it, so
+// an Iterator can pause, change direction and walk several trees in
+// step. It keeps an explicit stack of the path to its position, so
+// each step is amortized O(1) and does not change the tree.
+//
+// An Iterator is not safe for concurrent use but the tree is, and it
+// sees the tree as it was when the Iterator was made.
+type Iterator[T any] struct {
+	s stepper[T]
+}
+
+// stepper moves an Iterator, walkers do it for unchunked trees and
+// chunkWalkers for Chunked trees
+type stepper[T any] interface {
+	next() (T, bool)
+	prev() (T, bool)
+}
+
+// frame a step in a walker's path: a deep tree and the index of the
+// part of it the path goes through or a node and the index of its elem
+type frame[T, M any] struct {
+	deep  *deep[T, M]
+	items []elem[T, M]
+	index int
+}
+
+// walker steps through an unchunked tree, its stack is the path from
+// the root to the leaf it is beside
+type walker[T, M any] struct {
+	stack []frame[T, M]
+	leaf  elem[T, M]
+	// whether the tree has items
+	ok bool
+	// whether the position is after leaf rather than before it
+	after bool
+}
+
+// seekable trees with other representations, like Chunked, make their
+// own steppers
+type seekable[T, M any] interface {
+	seek(s seeker[M]) stepper[T]
+}
+
+// NewIterator return an Iterator before the first item of t
+func NewIterator[T, M any](t Fingertree[T, M]) *Iterator[T] {
+	if c, ok := t.(seekable[T, M]); ok {
+		return &Iterator[T]{c.seek(counted[M](0))}
+	}
+	w := &walker[T, M]{}
+	w.ok = w.enter(elem[T, M]{}, asSplittable(t), false)
+	return &Iterator[T]{w}
+}
+
+// IterFrom return an Iterator before the first item of t that
+// satisfies p or after the last item if none does, without splitting t
+func IterFrom[T, M any](t Fingertree[T, M], p Predicate[M]) *Iterator[T] {
+	return iterFrom(t, measured(p))
+}
+
+// IterAt return an Iterator before the item at index i of t or after
+// the last item if i >= t.Len()
+func IterAt[T, M any](t Fingertree[T, M], i int) *Iterator[T] {
+	return iterFrom(t, counted[M](i))
+}
+
+func iterFrom[T, M any](t Fingertree[T, M], s seeker[M]) *Iterator[T] {
+	if c, ok := t.(seekable[T, M]); ok {
+		return &Iterator[T]{c.seek(s)}
+	}
+	w := &walker[T, M]{}
+	w.seek(asSplittable(t), s)
+	return &Iterator[T]{w}
+}
+
+// Next return the item after the iterator and move past it, returns
+// false at the end of the tree
+func (it *Iterator[T]) Next() (T, bool) { return it.s.next() }
+
+// Prev return the item before the iterator and move before it,
+// returns false at the start of the tree
+func (it *Iterator[T]) Prev() (T, bool) { return it.s.prev() }
+
+// All return an iter.Seq of the items after the iterator, ranging
+// over it moves the iterator
+func (it *Iterator[T]) All() iter.Seq[T] { return it.pull(it.Next) }
+
+// Backward return an iter.Seq of the items before the iterator in
+// reverse, ranging over it moves the iterator
+func (it *Iterator[T]) Backward() iter.Seq[T] { return it.pull(it.Prev) }
+
+func (it *Iterator[T]) pull(step func() (T, bool)) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item, ok := step(); ok && yield(item); item, ok = step() {
+		}
+	}
+}
+
+// count return the number of parts of the frame: the left digit's
+// elems, the middle tree and the right digit's elems of a deep tree
+// or the elems of a node
+func (f *frame[T, M]) count() int {
+	if f.deep != nil {
+		return f.deep.left.count() + 1 + f.deep.right.count()
+	}
+	return len(f.items)
+}
+
+// part return the frame's part at i, which is an elem or a tree
+func (f *frame[T, M]) part(i int) (elem[T, M], splittable[T, M]) {
+	if f.deep == nil {
+		return f.items[i], nil
+	}
+	n := f.deep.left.count()
+	switch {
+	case i < n:
+		return f.deep.left.array[i], nil
+	case i == n:
+		return elem[T, M]{}, f.deep.middle
+	}
+	return f.deep.right.array[i-n-1], nil
+}
+
+// measure return the size and measurement of the frame's part at i
+func (f *frame[T, M]) measure(i int) (int, M) {
+	e, t := f.part(i)
+	if t != nil {
+		return t.Len(), t.Measure()
+	}
+	return e.size(), e.measure()
+}
+
+// parts return a frame for the parts of e, or tree t if it is not
+// nil, or e itself and true if it is an item. The frame of an empty
+// tree has no parts and singles are skipped.
+func parts[T, M any](e elem[T, M], t splittable[T, M]) (frame[T, M], elem[T, M], bool) {
+	var f frame[T, M]
+	if t != nil {
+		switch tree := t.force().(type) {
+		case *single[T, M]:
+			e = tree.item
+		case *deep[T, M]:
+			f.deep = tree
+			return f, e, false
+		default:
+			return f, e, false
+		}
+	}
+	if e.node != nil {
+		f.items = e.node.elems()
+		return f, e, false
+	}
+	return f, e, true
+}
+
+// enter push the path from e, or tree t if it is not nil, to its
+// first leaf or its last if last is true. Returns false if t is empty.
+func (w *walker[T, M]) enter(e elem[T, M], t splittable[T, M], last bool) bool {
+	for {
+		f, item, leaf := parts(e, t)
+		if leaf {
+			w.leaf = item
+			return true
+		}
+		if f.count() == 0 {
+			return false
+		}
+		if last {
+			f.index = f.count() - 1
+		}
+		w.stack = append(w.stack, f)
+		e, t = f.part(f.index)
+	}
+}
+
+// seek push the path from the root of t to the first leaf that
+// satisfies s and return the position before it, or push the path to
+// the last leaf and go after it if none does
+func (w *walker[T, M]) seek(t splittable[T, M], s seeker[M]) (i pos[M]) {
+	m := t.measurerOf()
+	i = start(m)
+	if t.IsEmpty() {
+		return
+	}
+	w.ok = true
+	if !s(pos[M]{t.Len(), t.Measure()}) {
+		w.enter(elem[T, M]{}, t, true)
+		w.after = true
+		return
+	}
+	var e elem[T, M]
+	for {
+		f, item, leaf := parts(e, t)
+		if leaf {
+			w.leaf = item
+			return i
+		}
+		// the last part is the fallback when s is not monotonic
+		for f.index = 0; f.index < f.count()-1; f.index++ {
+			size, measurement := f.measure(f.index)
+			next := i.add(m, size, measurement)
+			if s(next) {
+				break
+			}
+			i = next
+		}
+		w.stack = append(w.stack, f)
+		e, t = f.part(f.index)
+	}
+}
+
+// move go to the next leaf or the previous one if dir is -1, returns
+// false and leaves the walker alone if there is none
+func (w *walker[T, M]) move(dir int) bool {
+	// most steps go to a leaf beside the current one
+	if n := len(w.stack); n > 0 {
+		f := &w.stack[n-1]
+		if i := f.index + dir; f.deep == nil && 0 <= i && i < len(f.items) && f.items[i].node == nil {
+			f.index = i
+			w.leaf = f.items[i]
+			return true
+		}
+	}
+	for i := len(w.stack) - 1; i >= 0; i-- {
+		for f := &w.stack[i]; 0 <= f.index+dir && f.index+dir < f.count(); f = &w.stack[i] {
+			f.index += dir
+			w.stack = w.stack[:i+1]
+			// only an empty middle tree has no leaves, the digits
+			// around it always do
+			if e, t := f.part(f.index); w.enter(e, t, dir < 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (w *walker[T, M]) next() (item T, ok bool) {
+	if !w.ok || (w.after && !w.move(1)) {
+		return
+	}
+	w.after = true
+	return w.leaf.item, true
+}
+
+func (w *walker[T, M]) prev() (item T, ok bool) {
+	if !w.ok || (!w.after && !w.move(-1)) {
+		return
+	}
+	w.after = false
+	return w.leaf.item, true
+}
+
+// chunkWalker steps through a Chunked tree, it walks the chunks and
+// the items of the chunk it is in
+type chunkWalker[T, M any] struct {
+	chunks walker[*chunk[T, M], chunkMeasure[M]]
+	// the number of items in the current chunk before the position
+	index int
+}
+
+// seek return a chunkWalker before the first item of c that satisfies
+// s, or after the last item if none does
+func (c *Chunked[T, M]) seek(s seeker[M]) stepper[T] {
+	w, _ := c.walk(s)
+	return w
+}
+
+// walk return a chunkWalker like seek and the position before its
+// item
+func (c *Chunked[T, M]) walk(s seeker[M]) (*chunkWalker[T, M], pos[M]) {
+	m := c.measurer
+	w := &chunkWalker[T, M]{}
+	prefix := w.chunks.seek(asSplittable(c.tree), measured(c.chunkPredicate(s, start(m))))
+	i := pos[M]{prefix.measure.count, prefix.measure.measure}
+	if !w.chunks.ok {
+		return w, i
+	}
+	items := w.chunks.leaf.item.items
+	if w.chunks.after {
+		w.index = len(items)
+		return w, i
+	}
+	// scan the chunk like split does
+	for ; w.index < len(items)-1; w.index++ {
+		next := i.add(m, 1, m.Measure(items[w.index]))
+		if s(next) {
+			break
+		}
+		i = next
+	}
+	return w, i
+}
+
+func (w *chunkWalker[T, M]) next() (item T, ok bool) {
+	if !w.chunks.ok {
+		return
+	}
+	if w.index == len(w.chunks.leaf.item.items) {
+		if !w.chunks.move(1) {
+			return
+		}
+		w.index = 0
+	}
+	w.index++
+	return w.chunks.leaf.item.items[w.index-1], true
+}
+
+func (w *chunkWalker[T, M]) prev() (item T, ok bool) {
+	if !w.chunks.ok {
+		return
+	}
+	if w.index == 0 {
+		if !w.chunks.move(-1) {
+			return
+		}
+		w.index = len(w.chunks.leaf.item.items)
+	}
+	w.index--
+	return w.chunks.leaf.item.items[w.index], true
+}
+
+// FromSeq return a tree of the items in seq, making nodes as the items
+// arrive. FromSeq(m, slices.Values(items)) makes the same tree as
+// FromSlice(m, items).
+func FromSeq[T, M any](m MeasurerOf[T, M], seq iter.Seq[T]) Fingertree[T, M] {
+	b := &builder[T, M]{measurer: m}
+	for item := range seq {
+		b.add(leaf(m, item))
+	}
+	return b.finish()
+}
+
+// Enumerate return an iter.Seq2 of the index and item of each item in
+// t, maps.Collect(Enumerate(t)) makes a map of t's items by index
+func Enumerate[T, M any](t Fingertree[T, M]) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		i := 0
+		t.Each(func(item T) bool {
+			i++
+			return yield(i-1, item)
+		})
+	}
+}
+
+// KeyedBy return an iter.Seq2 of the key and item of each item in t,
+// maps.Collect(KeyedBy(t, key)) makes a map of t's items by key
+func KeyedBy[T, M any, K comparable](t Fingertree[T, M], key func(T) K) iter.Seq2[K, T] {
+	return func(yield func(K, T) bool) {
+		t.Each(func(item T) bool { return yield(key(item), item) })
+	}
+}
@@ -0,0 +1,63 @@
+package typed
+
+// Remeasure return a tree with t's items measured with m. It has the
+// same shape as t: every digit and node is copied with measurements
+// from m, so there is no rebalancing and each item is measured once.
+// Delayed subtrees of t are evaluated.
+func Remeasure[T, M, N any](t Fingertree[T, M], m MeasurerOf[T, N]) Fingertree[T, N] {
+	return Map(t, func(item T) T { return item }, m)
+}
+
+// Map return a tree with f of each of t's items, measured with m. Like
+// Remeasure, the tree has the same shape as t and a Chunked tree
+// keeps its chunks.
+func Map[T, M, U, N any](t Fingertree[T, M], f func(T) U, m MeasurerOf[U, N]) Fingertree[U, N] {
+	if c, ok := t.(*Chunked[T, M]); ok {
+		return mapChunked(c, f, m)
+	}
+	return mapTree(asSplittable(t), f, m)
+}
+
+// mapTree copy t's shape with f of its items, measured with m
+func mapTree[T, M, U, N any](t splittable[T, M], f func(T) U, m MeasurerOf[U, N]) splittable[U, N] {
+	switch tree := t.force().(type) {
+	case *single[T, M]:
+		return newSingle(m, mapElem(tree.item, f, m))
+	case *deep[T, M]:
+		return newDeep(m, mapDigit(tree.left, f, m), mapTree(tree.middle, f, m), mapDigit(tree.right, f, m))
+	}
+	return newEmpty(m)
+}
+
+func mapDigit[T, M, U, N any](d *digit[T, M], f func(T) U, m MeasurerOf[U, N]) *digit[U, N] {
+	var items [4]elem[U, N]
+	for i, e := range d.elems() {
+		items[i] = mapElem(e, f, m)
+	}
+	return newDigit(m, items[:d.n])
+}
+
+func mapElem[T, M, U, N any](e elem[T, M], f func(T) U, m MeasurerOf[U, N]) elem[U, N] {
+	if e.node == nil {
+		return leaf(m, f(e.item))
+	}
+	var items [3]elem[U, N]
+	for i, child := range e.node.elems() {
+		items[i] = mapElem(child, f, m)
+	}
+	return newNode(m, items[:e.node.n])
+}
+
+// mapChunked map the items of each of c's chunks into a new chunk and
+// copy the chunk tree's shape
+func mapChunked[T, M, U, N any](c *Chunked[T, M], f func(T) U, m MeasurerOf[U, N]) *Chunked[U, N] {
+	mapped := NewChunked(m, c.k)
+	mapChunk := func(ch *chunk[T, M]) *chunk[U, N] {
+		items := make([]U, len(ch.items))
+		for i, item := range ch.items {
+			items[i] = f(item)
+		}
+		return mapped.newChunk(items)
+	}
+	return mapped.with(mapTree(asSplittable(c.tree), mapChunk, mapped.chunkMeasurer))
+}
@@ -0,0 +1,141 @@
+package typed
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// piece a run of a tree's items, its function executes c on them
+// like Each
+type piece[T any] func(c Code[T]) bool
+
+// ParallelFold return the sum under m of the measurements of t's items,
+// like measuring t's items with m, using up to workers goroutines.
+// Each goroutine folds whole subtrees and the results are summed in
+// order, so m does not need to be commutative. m's Measure and Sum
+// must be safe for concurrent use. If workers < 1, ParallelFold uses
+// GOMAXPROCS goroutines.
+func ParallelFold[T, M, R any](t Fingertree[T, M], m MeasurerOf[T, R], workers int) R {
+	workers = poolSize(workers)
+	pieces := piecesOf(t, 4*workers)
+	results := make([]R, len(pieces))
+	runPieces(pieces, workers, func(i int) {
+		result := m.Identity()
+		pieces[i](func(item T) bool {
+			result = m.Sum(result, m.Measure(item))
+			return true
+		})
+		results[i] = result
+	})
+	result := m.Identity()
+	for _, r := range results {
+		result = m.Sum(result, r)
+	}
+	return result
+}
+
+// ParallelEach execute c on each item in t using up to workers
+// goroutines until c returns false or all the items have been
+// processed. Returns whether all of the items were processed. c runs
+// on items of different subtrees at the same time and in no
+// particular order, so it must be safe for concurrent use. Once c
+// returns false the goroutines stop at their next item. If workers <
+// 1, ParallelEach uses GOMAXPROCS goroutines.
+func ParallelEach[T, M any](t Fingertree[T, M], workers int, c Code[T]) bool {
+	workers = poolSize(workers)
+	pieces := piecesOf(t, 4*workers)
+	var stopped int32
+	runPieces(pieces, workers, func(i int) {
+		pieces[i](func(item T) bool {
+			if atomic.LoadInt32(&stopped) != 0 {
+				return false
+			}
+			if !c(item) {
+				atomic.StoreInt32(&stopped, 1)
+				return false
+			}
+			return true
+		})
+	})
+	return atomic.LoadInt32(&stopped) == 0
+}
+
+func poolSize(workers int) int {
+	if workers < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// runPieces run f on the index of each piece with a pool of workers
+// goroutines
+func runPieces[T any](pieces []piece[T], workers int, f func(i int)) {
+	var wg sync.WaitGroup
+	next := int64(-1)
+	for w := 0; w < min(workers, len(pieces)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(atomic.AddInt64(&next, 1)); i < len(pieces); i = int(atomic.AddInt64(&next, 1)) {
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// piecesOf split t into about n pieces, in order. Trees with other
+// representations, like Chunked, split themselves.
+func piecesOf[T, M any](t Fingertree[T, M], n int) []piece[T] {
+	if p, ok := t.(interface{ pieces(n int) []piece[T] }); ok {
+		return p.pieces(n)
+	}
+	s := asSplittable(t)
+	return appendPieces(nil, elem[T, M]{}, s, max(1, s.Len()/n))
+}
+
+// appendPieces append pieces for e, or tree t if it is not nil, that
+// have at most limit items unless they are single items
+func appendPieces[T, M any](pieces []piece[T], e elem[T, M], t splittable[T, M], limit int) []piece[T] {
+	f, item, leaf := parts(e, t)
+	if leaf {
+		return append(pieces, item.each)
+	}
+	for i := 0; i < f.count(); i++ {
+		e, t := f.part(i)
+		size := e.size()
+		if t != nil {
+			size = t.Len()
+		}
+		switch {
+		case size == 0:
+		case size > limit:
+			pieces = appendPieces(pieces, e, t, limit)
+		case t != nil:
+			pieces = append(pieces, t.Each)
+		default:
+			pieces = append(pieces, e.each)
+		}
+	}
+	return pieces
+}
+
+// pieces split the chunk tree and run c on the items of each chunk
+func (c *Chunked[T, M]) pieces(n int) []piece[T] {
+	chunkPieces := piecesOf(c.tree, n)
+	pieces := make([]piece[T], len(chunkPieces))
+	for i, each := range chunkPieces {
+		pieces[i] = func(f Code[T]) bool {
+			return each(func(ch *chunk[T, M]) bool {
+				for _, item := range ch.items {
+					if !f(item) {
+						return false
+					}
+				}
+				return true
+			})
+		}
+	}
+	return pieces
+}
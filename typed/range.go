@@ -0,0 +1,122 @@
+package typed
+
+// eachBetween run c on the items of t from the first one that
+// satisfies from up to the first one that satisfies to. It descends
+// once to the first item and steps from there, checking to with the
+// measurements the items keep.
+func eachBetween[T, M any](t splittable[T, M], from, to Predicate[M], c Code[T]) bool {
+	m := t.measurerOf()
+	w := &walker[T, M]{}
+	i := w.seek(t, measured(from))
+	for item, ok := w.next(); ok; item, ok = w.next() {
+		i = i.add(m, 1, w.leaf.measurement)
+		if to(i.measure) {
+			return true
+		}
+		if !c(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// rangeMeasure return the measurement of the items eachBetween visits
+func rangeMeasure[T, M any](t splittable[T, M], from, to Predicate[M]) M {
+	m := t.measurerOf()
+	return measureBetween(m, elem[T, M]{}, t, start(m), measured(from), measured(to),
+		func(item elem[T, M], i pos[M]) M {
+			if next := i.add(m, 1, item.measurement); from(next.measure) && !to(next.measure) {
+				return item.measurement
+			}
+			return m.Identity()
+		})
+}
+
+// measureBetween return the measurement of the items of e, or tree t
+// if it is not nil, that are between from and to, where i is the
+// position before them. Parts inside the range use their cached
+// measurements and only the parts where from or to become true are
+// opened, like a segment tree query, so both ends of the range are
+// found in the same descent. leaf measures the part of a leaf that
+// is in the range.
+func measureBetween[T, M any](m MeasurerOf[T, M], e elem[T, M], t splittable[T, M], i pos[M], from, to seeker[M], leaf func(item elem[T, M], i pos[M]) M) M {
+	result := m.Identity()
+	f, item, isLeaf := parts(e, t)
+	if isLeaf {
+		return leaf(item, i)
+	}
+	for n := 0; n < f.count() && !to(i); n++ {
+		size, measurement := f.measure(n)
+		next := i.add(m, size, measurement)
+		switch {
+		case from(i) && !to(next):
+			result = m.Sum(result, measurement)
+		case from(next):
+			e, t := f.part(n)
+			result = m.Sum(result, measureBetween(m, e, t, i, from, to, leaf))
+		}
+		i = next
+	}
+	return result
+}
+
+// eachBetween step through the chunks from the one that holds the
+// first item in the range, running c on whole chunks until the one
+// where to becomes true, which is scanned item by item
+func (c *Chunked[T, M]) eachBetween(from, to Predicate[M], f Code[T]) bool {
+	m := c.measurer
+	w, i := c.walk(measured(from))
+	if !w.chunks.ok {
+		return true
+	}
+	for {
+		ch := w.chunks.leaf.item
+		whole := w.index == 0 && !to(i.add(m, len(ch.items), ch.measurement).measure)
+		for _, item := range ch.items[w.index:] {
+			if !whole {
+				i = i.add(m, 1, m.Measure(item))
+				if to(i.measure) {
+					return true
+				}
+			}
+			if !f(item) {
+				return false
+			}
+		}
+		if whole {
+			i = i.add(m, len(ch.items), ch.measurement)
+		}
+		if !w.chunks.move(1) {
+			return true
+		}
+		w.index = 0
+	}
+}
+
+// rangeMeasure measure the chunks inside the range from the chunk
+// tree and the items of the chunks at its ends one by one
+func (c *Chunked[T, M]) rangeMeasure(from, to Predicate[M]) M {
+	m := c.measurer
+	chunks := asSplittable(c.tree)
+	cm := chunks.measurerOf()
+	items := func(p Predicate[M]) seeker[chunkMeasure[M]] {
+		return func(i pos[chunkMeasure[M]]) bool { return p(i.measure.measure) }
+	}
+	result := measureBetween(cm, elem[*chunk[T, M], chunkMeasure[M]]{}, chunks, start(cm), items(from), items(to),
+		func(e elem[*chunk[T, M], chunkMeasure[M]], before pos[chunkMeasure[M]]) chunkMeasure[M] {
+			result := m.Identity()
+			i := pos[M]{before.measure.count, before.measure.measure}
+			for _, item := range e.item.items {
+				measurement := m.Measure(item)
+				i = i.add(m, 1, measurement)
+				if to(i.measure) {
+					break
+				}
+				if from(i.measure) {
+					result = m.Sum(result, measurement)
+				}
+			}
+			return chunkMeasure[M]{measure: result}
+		})
+	return result.measure
+}
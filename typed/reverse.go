@@ -0,0 +1,101 @@
+package typed
+
+// dualMeasurer a measurer with the arguments to Sum swapped
+type dualMeasurer[T, M any] struct {
+	MeasurerOf[T, M]
+}
+
+func (m dualMeasurer[T, M]) Sum(m1 M, m2 M) M { return m.MeasurerOf.Sum(m2, m1) }
+
+// Dual return a measurer like m with the arguments to Sum swapped, so
+// a tree measured with Dual(m) has the measurement m gives its items
+// read backwards. This keeps measurements that depend on order, like
+// string concatenation or function composition, correct in reversed
+// trees. Dual(Dual(m)) is m and Dual keeps m's strictness.
+func Dual[T, M any](m MeasurerOf[T, M]) MeasurerOf[T, M] {
+	inner := m
+	if s, ok := m.(strictMeasurer[T, M]); ok {
+		inner = s.MeasurerOf
+	}
+	var result MeasurerOf[T, M] = dualMeasurer[T, M]{inner}
+	if d, ok := inner.(dualMeasurer[T, M]); ok {
+		result = d.MeasurerOf
+	}
+	if isStrict(m) {
+		return Strict(result)
+	}
+	return result
+}
+
+// Reverse return a tree with t's items in reverse order, measured with
+// Dual of t's measurer. It mirrors t's shape, swapping the left and
+// right digits and the order of the elems in each digit and node.
+// Under the dual measurer every part of t has the same measurement
+// read backwards, so the reversed tree reuses t's measurements and no
+// item is measured again. Reverse is an O(n) copy: it copies every
+// digit and node of t, evaluating t's delayed subtrees, and the
+// reversed tree shares none of them with t. Reverse of a Chunked tree
+// is a Chunked tree with its chunks reversed.
+// SplittableItems are not reversed, so their Split sees them forwards.
+//
+// The reversed tree has the same type as t but a different measurer,
+// so it must only be concatenated with other trees measured with
+// Dual(m), such as other reversed trees. Mixing them with trees
+// measured with m gives wrong measurements when m is not commutative.
+// Reversing a reversed tree measures it with m again, since
+// Dual(Dual(m)) is m, so Reverse(Reverse(t)) can be combined with t.
+func Reverse[T, M any](t Fingertree[T, M]) Fingertree[T, M] {
+	if c, ok := t.(*Chunked[T, M]); ok {
+		return reverseChunked(c)
+	}
+	s := asSplittable(t)
+	return reverseTree(s, Dual(s.measurerOf()), func(item T) T { return item })
+}
+
+// reverseTree mirror t's shape with f of its items, measured with m,
+// which is the dual of t's measurer
+func reverseTree[T, M any](t splittable[T, M], m MeasurerOf[T, M], f func(T) T) splittable[T, M] {
+	switch tree := t.force().(type) {
+	case *single[T, M]:
+		return newSingle(m, reverseElem(tree.item, f))
+	case *deep[T, M]:
+		d := newDeep(m, reverseDigit(tree.right, f), reverseTree(tree.middle, m, f), reverseDigit(tree.left, f))
+		d.measured.Do(func() { d.measurement = tree.Measure() })
+		return d
+	}
+	return newEmpty(m)
+}
+
+func reverseDigit[T, M any](d *digit[T, M], f func(T) T) *digit[T, M] {
+	result := &digit[T, M]{n: d.n, size: d.size, measurement: d.measurement}
+	for i, e := range d.elems() {
+		result.array[int(d.n)-1-i] = reverseElem(e, f)
+	}
+	return result
+}
+
+func reverseElem[T, M any](e elem[T, M], f func(T) T) elem[T, M] {
+	if e.node == nil {
+		return elem[T, M]{item: f(e.item), measurement: e.measurement}
+	}
+	n := e.node
+	result := &node[T, M]{n: n.n, size: n.size, measurement: n.measurement}
+	for i, child := range n.elems() {
+		result.array[int(n.n)-1-i] = reverseElem(child, f)
+	}
+	return elem[T, M]{node: result}
+}
+
+// reverseChunked reverse the chunk tree and the items in each chunk,
+// the chunks keep their measurements
+func reverseChunked[T, M any](c *Chunked[T, M]) *Chunked[T, M] {
+	reversed := NewChunked(Dual(c.measurer), c.k)
+	reverseChunk := func(ch *chunk[T, M]) *chunk[T, M] {
+		items := make([]T, len(ch.items))
+		for i, item := range ch.items {
+			items[len(items)-1-i] = item
+		}
+		return &chunk[T, M]{items, ch.measurement}
+	}
+	return reversed.with(reverseTree(asSplittable(c.tree), reversed.chunkMeasurer, reverseChunk))
+}
@@ -0,0 +1,102 @@
+package typed
+
+// SearchPredicate a function that returns whether a split point
+// matches, given the measurements of the items to its left and to its
+// right
+type SearchPredicate[M any] func(left, right M) bool
+
+// search3 split t around the first item x where p holds between x and
+// the items after it. This is Hinze and Paterson's search: p must be
+// false at the start and true at the end for a meaningful result and
+// once it becomes true it should stay true.
+func search3[T, M any](t splittable[T, M], p SearchPredicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	var none T
+
+	m := t.measurerOf()
+	if t.IsEmpty() || !p(t.Measure(), m.Identity()) {
+		return t, none, newEmpty(m), t.Measure(), false
+	}
+	split := t.searchTree(p, start(m), m.Identity())
+	return split.left, split.mid.item, split.right, split.before.measure, true
+}
+
+func (d *digit[T, M]) search(m MeasurerOf[T, M], p SearchPredicate[M], i pos[M], after M) digitSplit[T, M] {
+	return searchItems(m, d.elems(), p, i, after)
+}
+
+// searchItems find the first of the elems of a digit or node where p
+// holds, after is the measurement of everything to the right of them
+func searchItems[T, M any](m MeasurerOf[T, M], items []elem[T, M], p SearchPredicate[M], i pos[M], after M) digitSplit[T, M] {
+	var suffixes [4]M
+
+	if len(items) == 1 {
+		return digitSplit[T, M]{nil, items[0], nil, i}
+	}
+	suffixes[len(items)-1] = after
+	for n := len(items) - 2; n >= 0; n-- {
+		suffixes[n] = m.Sum(items[n+1].measure(), suffixes[n+1])
+	}
+	n := 0
+	for ; n < len(items)-1; n++ {
+		next := i.add(m, items[n].size(), items[n].measure())
+		if p(next.measure, suffixes[n]) {
+			break
+		}
+		i = next
+	}
+	return digitSplit[T, M]{items[0:n:n], items[n], items[n+1:], i}
+}
+
+func (e *empty[T, M]) Search(p SearchPredicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	return search3[T, M](e, p)
+}
+func (e *empty[T, M]) searchTree(p SearchPredicate[M], i pos[M], after M) treeSplit[T, M] {
+	return treeSplit[T, M]{left: e, right: e, before: i}
+}
+
+func (s *single[T, M]) Search(p SearchPredicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	return search3[T, M](s, p)
+}
+func (s *single[T, M]) searchTree(p SearchPredicate[M], i pos[M], after M) treeSplit[T, M] {
+	return treeSplit[T, M]{newEmpty(s.measurer), s.item, newEmpty(s.measurer), i}
+}
+
+func (d *deep[T, M]) Search(p SearchPredicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	return search3[T, M](d, p)
+}
+func (d *deep[T, M]) searchTree(p SearchPredicate[M], i pos[M], after M) treeSplit[T, M] {
+	m := d.measurer
+	rightAfter := m.Sum(d.right.measurement, after)
+	midAfter := m.Sum(d.middle.Measure(), rightAfter)
+	leftPos := i.add(m, d.left.size, d.left.measurement)
+	if p(leftPos.measure, midAfter) {
+		dsplit := d.left.search(m, p, i, midAfter)
+		return treeSplit[T, M]{fromArray(m, dsplit.left),
+			dsplit.mid,
+			deepLeft(m, dsplit.right, d.middle, d.right),
+			dsplit.before}
+	}
+	midPos := leftPos.add(m, d.middle.Len(), d.middle.Measure())
+	if p(midPos.measure, rightAfter) {
+		midSplit := d.middle.searchTree(p, leftPos, rightAfter)
+		split := searchItems(m, midSplit.mid.node.elems(), p,
+			leftPos.add(m, midSplit.left.Len(), midSplit.left.Measure()),
+			m.Sum(midSplit.right.Measure(), rightAfter))
+		return treeSplit[T, M]{deepRight(m, d.left, midSplit.left, split.left),
+			split.mid,
+			deepLeft(m, split.right, midSplit.right, d.right),
+			split.before}
+	}
+	dsplit := d.right.search(m, p, midPos, after)
+	return treeSplit[T, M]{deepRight(m, d.left, d.middle, dsplit.left),
+		dsplit.mid,
+		fromArray(m, dsplit.right),
+		dsplit.before}
+}
+
+func (d *delayedFingertree[T, M]) Search(p SearchPredicate[M]) (Fingertree[T, M], T, Fingertree[T, M], M, bool) {
+	return d.force().Search(p)
+}
+func (d *delayedFingertree[T, M]) searchTree(p SearchPredicate[M], i pos[M], after M) treeSplit[T, M] {
+	return d.force().searchTree(p, i, after)
+}